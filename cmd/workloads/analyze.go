@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/checker"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// analyze re-runs the checkers over a history recorded with -history, so old
+// runs can be analyzed with improved checkers without rerunning the cluster.
+func analyze(path string, args []string) error {
+	fs := flag.NewFlagSet("workloads analyze", flag.ExitOnError)
+	checkers := fs.String("checkers", strings.Join(checker.AllCheckers, ","), "comma-separated checkers to run")
+	opts := checker.Options{LinearizabilityTimeout: time.Minute, BucketSize: time.Second}
+	fs.DurationVar(&opts.LinearizabilityTimeout, "linearizability-timeout", opts.LinearizabilityTimeout, "give up the linearizability check after this long, 0 for no limit")
+	fs.DurationVar(&opts.BucketSize, "bucket-size", opts.BucketSize, "resolution of the unavailability intervals")
+	minAvailability := fs.Float64("min-availability", 0, "fail when fewer than this fraction of writes succeeded")
+	format := fs.String("output", "text", "report format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	for _, name := range strings.Split(*checkers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.Checkers = append(opts.Checkers, name)
+		}
+	}
+
+	ops, err := history.Load(path)
+	if err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+	analysis, err := checker.Analyze(ops, opts)
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	if analysis.Valid == checker.Invalid {
+		failures = append(failures, "anomalies found")
+	}
+	if a := analysis.Availability; a != nil && a.Availability < *minAvailability {
+		failures = append(failures, fmt.Sprintf("availability %f below %f", a.Availability, *minAvailability))
+	}
+
+	switch *format {
+	case "text":
+		printAnalysis(path, analysis, failures)
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(analysis); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func printAnalysis(path string, analysis checker.Analysis, failures []string) {
+	reporter := workload.NewTextReporter(os.Stdout)
+	var line strings.Builder
+	fmt.Fprintf(&line, "Analysis: history [%s], ops [%d]", path, analysis.Ops)
+	for _, result := range analysis.Results {
+		for _, a := range result.Anomalies {
+			reporter.Anomaly(workload.AnomalyEvent{
+				Kind:    a.Kind,
+				Key:     a.Key,
+				Message: a.Message,
+				Ops:     a.Ops,
+			})
+		}
+		for _, op := range result.Recovered {
+			fmt.Printf("Indeterminate write applied: %s\n", op)
+		}
+		if result.Detail != "" {
+			fmt.Printf("Checker [%s]: %s\n", result.Checker, result.Detail)
+		}
+		fmt.Fprintf(&line, ", %s [%s", result.Checker, result.Valid)
+		if len(result.Anomalies) > 0 {
+			fmt.Fprintf(&line, ", %d anomalies", len(result.Anomalies))
+		}
+		if len(result.Recovered) > 0 {
+			fmt.Fprintf(&line, ", %d recovered", len(result.Recovered))
+		}
+		line.WriteString("]")
+	}
+	if a := analysis.Availability; a != nil {
+		for _, interval := range a.Unavailable {
+			fmt.Printf("Unavailable: [%s, %s], Writes: [%d]\n",
+				interval.Start.Format(time.RFC3339Nano), interval.End.Format(time.RFC3339Nano), interval.Writes)
+		}
+		fmt.Fprintf(&line, ", availability [%f]", a.Availability)
+	}
+	if len(failures) == 0 {
+		line.WriteString(", PASSED")
+	} else {
+		fmt.Fprintf(&line, ", FAILED [%s]", strings.Join(failures, "; "))
+	}
+	fmt.Println(line.String())
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/cassandra"
+)
+
+func cassandraBackend(fs *flag.FlagSet, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	cfg := &cassandra.Config{}
+	port, err := strconv.Atoi(workload.GetEnvWithDefault("CASSANDRA_PORT", "9042"))
+	if err != nil {
+		port = 9042
+	}
+	fs.StringVar(&cfg.Host, "host", workload.GetEnvWithDefault("CASSANDRA_HOST", "development-test-cluster-service.cass-operator.svc.cluster.local"), "contact point (env CASSANDRA_HOST)")
+	fs.IntVar(&cfg.Port, "port", port, "native protocol port (env CASSANDRA_PORT)")
+	fs.StringVar(&cfg.User, "user", workload.GetEnvWithDefault("CASSANDRA_USER", ""), "user (env CASSANDRA_USER)")
+	fs.StringVar(&cfg.Password, "password", workload.GetEnvWithDefault("CASSANDRA_PASSWORD", ""), "password (env CASSANDRA_PASSWORD)")
+
+	return func(ctx context.Context) (workload.Driver, error) {
+		if err := workload.WaitForHost(ctx, cfg.Host); err != nil {
+			return nil, err
+		}
+		cfg.Record = wcfg.Record
+		return cassandra.NewDriver(*cfg)
+	}
+}
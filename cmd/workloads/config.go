@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const envPrefix = "WORKLOAD_"
+
+// parseLayered parses args into fs after adding the workload flags of wfs
+// and a -config flag. Settings are layered, later ones winning:
+//
+//   - flag defaults, which for connection flags already come from the
+//     backend's environment variables,
+//   - WORKLOAD_<FLAG> environment variables for the workload flags,
+//     e.g. WORKLOAD_RATE or WORKLOAD_KEY_SPACE,
+//   - the JSON config file given by -config or WORKLOAD_CONFIG, an object
+//     keyed by flag name, e.g. {"rate": 20, "duration": "10m"},
+//   - flags given on the command line.
+//
+// Within a layer, presets such as -profile are set first, so the other
+// settings of the layer override what they preset.
+func parseLayered(fs, wfs *flag.FlagSet, args []string) error {
+	wfs.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage+" (env "+envName(f.Name)+")")
+	})
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "JSON file with flag values (env "+envPrefix+"CONFIG)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	env := map[string]string{}
+	wfs.VisitAll(func(f *flag.Flag) {
+		if value, ok := os.LookupEnv(envName(f.Name)); ok {
+			env[f.Name] = value
+		}
+	})
+	if err := setLayer(fs, env, func(name string, err error) error {
+		return fmt.Errorf("%s: %w", envName(name), err)
+	}); err != nil {
+		return err
+	}
+
+	if *configPath != "" {
+		if err := loadConfigFile(fs, *configPath); err != nil {
+			return err
+		}
+	}
+
+	return setLayer(fs, explicit, func(_ string, err error) error { return err })
+}
+
+// presetValue is implemented by flag values that set other flags.
+type presetValue interface {
+	flag.Value
+	preset()
+}
+
+// setLayer sets the flags of one layer of settings, the presets first.
+// wrap adds the source of a setting to its error.
+func setLayer(fs *flag.FlagSet, values map[string]string, wrap func(name string, err error) error) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return isPreset(fs, names[i]) && !isPreset(fs, names[j])
+	})
+	for _, name := range names {
+		if err := fs.Set(name, values[name]); err != nil {
+			return wrap(name, err)
+		}
+	}
+	return nil
+}
+
+func isPreset(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	_, ok := f.Value.(presetValue)
+	return ok
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	defer f.Close()
+
+	values := map[string]any{}
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	settings := map[string]string{}
+	for name, value := range values {
+		if name == "config" {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("config %s: unknown setting %q", path, name)
+		}
+		settings[name] = fmt.Sprint(value)
+	}
+	return setLayer(fs, settings, func(name string, err error) error {
+		return fmt.Errorf("config %s: %s: %w", path, name, err)
+	})
+}
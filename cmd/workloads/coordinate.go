@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/coordinator"
+)
+
+// coordinate serves a coordinator that the workloads started with
+// -coordinator push their results to, and prints the merged cluster-wide
+// timeline.
+func coordinate(args []string) error {
+	fs := flag.NewFlagSet("workloads coordinate", flag.ExitOnError)
+	addr := fs.String("addr", workload.GetEnvWithDefault("COORDINATOR_ADDR", ":8080"), "address to serve the coordinator on (env COORDINATOR_ADDR)")
+	bucket := fs.Duration("bucket-size", time.Second, "merge the intervals of all instances into buckets of this size")
+	delay := fs.Duration("delay", 10*time.Second, "print a bucket once it is this old, so all instances have reported it")
+	clients := fs.Int("clients", 0, "exit once this many instances finished their run, 0 to run until killed")
+	format := fs.String("output", "text", "report format: text or json (one JSON object per line)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *bucket <= 0 {
+		return fmt.Errorf("bucket size must be positive, got %s", *bucket)
+	}
+
+	var reporter workload.Reporter
+	var emit func(any)
+	switch *format {
+	case "text":
+		reporter = workload.NewTextReporter(os.Stdout)
+		emit = printCluster
+	case "json":
+		reporter = workload.NewJSONReporter(os.Stdout)
+		enc := json.NewEncoder(os.Stdout)
+		emit = func(v any) {
+			switch v := v.(type) {
+			case coordinator.Point:
+				v.Type = "point"
+				enc.Encode(v)
+			case coordinator.ClusterSummary:
+				v.Type = "cluster"
+				enc.Encode(v)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+
+	c := coordinator.New(*bucket, reporter)
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: c.Handler()}
+	// A failed server never hears from the clients again, so it ends the
+	// loop instead of waiting for them.
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	fmt.Fprintf(os.Stderr, "Coordinator listening on %s\n", listener.Addr())
+
+	ctx, stop := shutdownContext()
+	defer stop()
+	ticker := time.NewTicker(*bucket)
+	defer ticker.Stop()
+	var serveErr error
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			done = true
+		case err := <-served:
+			fmt.Fprintf(os.Stderr, "Error serving coordinator: %s\n", err)
+			serveErr = fmt.Errorf("serving coordinator: %w", err)
+			done = true
+		case now := <-ticker.C:
+			for _, p := range c.Settled(now.Add(-*delay)) {
+				emit(p)
+			}
+			done = *clients > 0 && c.Finished() >= *clients
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+
+	for _, p := range c.Settled(time.Now().Add(*bucket)) {
+		emit(p)
+	}
+	s := c.Summary()
+	emit(s)
+	if serveErr != nil {
+		return serveErr
+	}
+	if !s.Passed {
+		return errors.New(strings.Join(s.Violations, "; "))
+	}
+	return nil
+}
+
+// printCluster prints a point of the timeline or the cluster summary in the
+// text format of the workload reports.
+func printCluster(v any) {
+	var line strings.Builder
+	switch v := v.(type) {
+	case coordinator.Point:
+		fmt.Fprintf(&line, "TS: [%s], Clients: [%d], Ops: [%d], Throughput: [%f], Availability: [%f]",
+			v.Time.Format(time.RFC3339), v.Clients, v.Ops, v.Throughput, v.Availability)
+		for _, l := range v.Latencies {
+			fmt.Fprintf(&line, ", %s: [%d, p50 %s, p99 %s, max %s]", l.Op, l.Count,
+				msToDuration(l.P50Ms), msToDuration(l.P99Ms), msToDuration(l.MaxMs))
+		}
+	case coordinator.ClusterSummary:
+		fmt.Fprintf(&line, "Cluster: clients [%d finished of %d], ops [%d], availability [%f]",
+			v.Finished, v.Clients, v.Ops, v.Availability)
+		for _, kind := range sortedKinds(v.Anomalies) {
+			fmt.Fprintf(&line, ", %s [%d]", kind, v.Anomalies[kind])
+		}
+		if v.Passed {
+			line.WriteString(", PASSED")
+		} else {
+			fmt.Fprintf(&line, ", FAILED [%s]", strings.Join(v.Violations, "; "))
+		}
+	}
+	fmt.Println(line.String())
+}
+
+func sortedKinds(m map[string]int) []string {
+	kinds := make([]string, 0, len(m))
+	for kind := range m {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func msToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
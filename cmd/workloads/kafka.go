@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/kafka"
+)
+
+func kafkaBackend(fs *flag.FlagSet, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	cfg := &kafka.Config{}
+	fs.StringVar(&cfg.Host, "host", workload.GetEnvWithDefault("KAFKA_HOST", "localhost"), "bootstrap host (env KAFKA_HOST)")
+	fs.StringVar(&cfg.Port, "port", workload.GetEnvWithDefault("KAFKA_PORT", "9092"), "bootstrap port (env KAFKA_PORT)")
+	fs.StringVar(&cfg.User, "user", workload.GetEnvWithDefault("KAFKA_USER", "user"), "SASL user (env KAFKA_USER)")
+	fs.StringVar(&cfg.Password, "password", workload.GetEnvWithDefault("KAFKA_PASSWORD", "password"), "SASL password (env KAFKA_PASSWORD)")
+	fs.StringVar(&cfg.Topic, "topic", workload.GetEnvWithDefault("KAFKA_TOPIC", "topic"), "topic to produce to (env KAFKA_TOPIC)")
+
+	return func(ctx context.Context) (workload.Driver, error) {
+		if err := workload.WaitForHost(ctx, cfg.Host); err != nil {
+			return nil, err
+		}
+		return kafka.NewDriver(*cfg, wcfg.KeySpace)
+	}
+}
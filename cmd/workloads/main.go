@@ -0,0 +1,75 @@
+// Command workloads runs a workload against one of the supported backends.
+//
+//	workloads run <backend> [flags]
+//	workloads receive rabbitmq [flags]
+//	workloads analyze <history> [flags]
+//	workloads coordinate [flags]
+//
+// Every flag defaults to the environment variable the per-backend writers
+// used to read, so existing manifests only need to add the args.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+	fmt.Fprintf(os.Stderr, "  workloads run <%s> [flags]\n", strings.Join(backendNames(), "|"))
+	fmt.Fprintf(os.Stderr, "  workloads receive rabbitmq [flags]\n")
+	fmt.Fprintf(os.Stderr, "  workloads analyze <history> [flags]\n")
+	fmt.Fprintf(os.Stderr, "  workloads coordinate [flags]\n")
+	fmt.Fprintf(os.Stderr, "\nRun 'workloads run <backend> -h' for the flags of a backend.\n")
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func main() {
+	if len(os.Args) < 2 || (len(os.Args) < 3 && os.Args[1] != "coordinate") {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "coordinate":
+		err = coordinate(os.Args[2:])
+	case "run":
+		err = run(os.Args[2], os.Args[3:])
+	case "receive":
+		err = receive(os.Args[2], os.Args[3:])
+	case "analyze":
+		err = analyze(os.Args[2], os.Args[3:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// shutdownContext returns a context that is cancelled on SIGINT or SIGTERM.
+// A second signal kills the process right away.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/mongodb"
+)
+
+func mongodbBackend(fs *flag.FlagSet, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	wcfg.Rate = 30
+	wcfg.SkipUntilFirstSuccess = true
+
+	cfg := &mongodb.Config{}
+	fs.StringVar(&cfg.Host, "host", workload.GetEnvWithDefault("MONGO_HOST", "test-cluster-mongos.acto-namespace.svc.cluster.local"), "mongos host (env MONGO_HOST)")
+	fs.StringVar(&cfg.Port, "port", workload.GetEnvWithDefault("MONGO_PORT", "27017"), "mongos port (env MONGO_PORT)")
+	fs.StringVar(&cfg.User, "user", workload.GetEnvWithDefault("MONGO_USER", "root"), "user (env MONGO_USER)")
+	fs.StringVar(&cfg.Password, "password", workload.GetEnvWithDefault("MONGO_PASSWORD", ""), "password (env MONGO_PASSWORD)")
+
+	return func(ctx context.Context) (workload.Driver, error) {
+		if err := workload.WaitForHost(ctx, cfg.Host); err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		cfg.Record = wcfg.Record
+		return mongodb.NewDriver(ctx, *cfg)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/rabbitmq"
+)
+
+func registerRabbitMQFlags(fs *flag.FlagSet, cfg *rabbitmq.Config) {
+	fs.StringVar(&cfg.Host, "host", workload.GetEnvWithDefault("SECRET_HOST", "localhost"), "broker host (env SECRET_HOST)")
+	fs.StringVar(&cfg.User, "user", workload.GetEnvWithDefault("SECRET_USERNAME", ""), "user (env SECRET_USERNAME)")
+	fs.StringVar(&cfg.Password, "password", workload.GetEnvWithDefault("SECRET_PASSWORD", ""), "password (env SECRET_PASSWORD)")
+}
+
+func rabbitmqBackend(fs *flag.FlagSet, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	wcfg.Rate = 1
+
+	cfg := &rabbitmq.Config{}
+	registerRabbitMQFlags(fs, cfg)
+
+	return func(ctx context.Context) (workload.Driver, error) {
+		if err := workload.WaitForHost(ctx, cfg.Host); err != nil {
+			return nil, err
+		}
+		return rabbitmq.NewDriver(*cfg, wcfg.KeySpace)
+	}
+}
+
+func receive(name string, args []string) error {
+	if name != "rabbitmq" {
+		usage()
+		return fmt.Errorf("receive is only supported for rabbitmq, not %q", name)
+	}
+
+	fs := flag.NewFlagSet("workloads receive "+name, flag.ExitOnError)
+	cfg := &rabbitmq.Config{}
+	registerRabbitMQFlags(fs, cfg)
+	metricsAddr := registerMetricsFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	workload.ServeMetrics(*metricsAddr)
+
+	ctx, stop := shutdownContext()
+	defer stop()
+	if err := workload.WaitForHost(ctx, cfg.Host); err != nil {
+		return err
+	}
+	return rabbitmq.Receive(ctx, *cfg)
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/coordinator"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// A backend registers its flags on fs, may adjust the shared defaults in cfg,
+// and returns a function that connects once the flags have been parsed.
+type backend func(fs *flag.FlagSet, cfg *workload.Config) func(ctx context.Context) (workload.Driver, error)
+
+var backends = map[string]backend{
+	"tidb":      tidbBackend,
+	"mariadb":   mariadbBackend,
+	"mongodb":   mongodbBackend,
+	"cassandra": cassandraBackend,
+	"kafka":     kafkaBackend,
+	"rabbitmq":  rabbitmqBackend,
+}
+
+func registerWorkloadFlags(fs *flag.FlagSet, cfg *workload.Config) {
+	fs.Float64Var(&cfg.Rate, "rate", cfg.Rate, "number of writes issued per second")
+	fs.Var(&scheduleFlag{cfg: cfg}, "schedule", "load shape overriding -rate, e.g. ramp:from=10,to=100,over=5m (see workload.ParseSchedule)")
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "stop after this long, 0 runs until killed")
+	fs.IntVar(&cfg.Operations, "operations", cfg.Operations, "stop after this many writes, 0 means no limit")
+	fs.DurationVar(&cfg.BucketSize, "bucket-size", cfg.BucketSize, "length of the buckets the success rate is reported in")
+	fs.Var((*durationsFlag)(&cfg.Windows), "windows", "comma-separated sliding windows every bucket is summarized over")
+	fs.IntVar(&cfg.KeySpace, "key-space", cfg.KeySpace, "number of distinct keys written")
+	fs.StringVar(&cfg.KeyDistribution, "key-distribution", cfg.KeyDistribution, "how keys are picked: sequential, uniform, zipfian[:theta=0.99], hotspot[:keys=0.2,ops=0.8] or latest[:theta=0.99]")
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "seed of the random key distributions and the operation mix")
+	fs.StringVar(&cfg.Mix, "mix", cfg.Mix, "weighted operations, e.g. read=50,update=40,delete=5,scan=5, of read, insert, update, upsert, delete and scan; empty only writes")
+	fs.IntVar(&cfg.ScanLength, "scan-length", cfg.ScanLength, "number of keys a scan reads")
+	fs.Var(&profileFlag{cfg: cfg}, "profile", "YCSB core workload a to f presetting -mix, -key-distribution, -scan-length and -preload, and storing the keys in the shared record schema")
+	fs.BoolVar(&cfg.Preload, "preload", cfg.Preload, "insert every key once before the mix starts, like the YCSB load phase")
+	fs.StringVar(&cfg.Client, "client-id", cfg.Client, "id of this instance, defaults to the pod name (env POD_NAME) or hostname")
+	fs.IntVar(&cfg.Instance, "instance", cfg.Instance, "index of this instance, -1 takes the ordinal of a StatefulSet pod from the client id")
+	fs.IntVar(&cfg.Instances, "instances", cfg.Instances, "number of instances writing to the backend, each to its own key-space partition")
+	fs.BoolVar(&cfg.CrossCheck, "cross-check", cfg.CrossCheck, "also check that the keys of the other instances never go back")
+	fs.DurationVar(&cfg.OpTimeout, "op-timeout", cfg.OpTimeout, "timeout of a single write or read")
+	fs.IntVar(&cfg.MaxInFlight, "max-in-flight", cfg.MaxInFlight, "maximum number of outstanding writes")
+	fs.IntVar(&cfg.QueueSize, "queue-size", cfg.QueueSize, "number of writes that may wait for a free slot before writes are dropped")
+	fs.BoolVar(&cfg.SkipUntilFirstSuccess, "skip-until-first-success", cfg.SkipUntilFirstSuccess, "ignore results until the first write succeeds")
+	fs.IntVar(&cfg.Thresholds.MaxAnomalies, "max-anomalies", cfg.Thresholds.MaxAnomalies, "fail the run with more anomalies than this, negative to disable")
+	fs.Float64Var(&cfg.Thresholds.MinAvailability, "min-availability", cfg.Thresholds.MinAvailability, "fail the run when fewer than this fraction of writes succeed")
+	fs.Float64Var(&cfg.Thresholds.MinReadAvailability, "min-read-availability", cfg.Thresholds.MinReadAvailability, "fail the run when fewer than this fraction of the reads and scans of -mix succeed")
+	fs.Var(&probeFlag{cfg: cfg, spec: "driver"}, "probe", "health probe to pass before the final read: driver, tcp:host:port, http(s)://url or none")
+	fs.DurationVar(&cfg.RecoveryTimeout, "recovery-timeout", cfg.RecoveryTimeout, "how long to wait for the probe before the final read starts anyway")
+	fs.DurationVar(&cfg.FinalReadTimeout, "final-read-timeout", cfg.FinalReadTimeout, "timeout of the final read of a whole set by the set workload")
+	fs.StringVar(&cfg.Checkpoint, "checkpoint", cfg.Checkpoint, "file to save the sequence and expected state to and resume from after a restart, e.g. on a PVC")
+	fs.DurationVar(&cfg.CheckpointInterval, "checkpoint-interval", cfg.CheckpointInterval, "how often the checkpoint is saved")
+	fs.BoolVar(&cfg.Linearizability, "linearizability", cfg.Linearizability, "check the recorded -history for linearizability at the end of the run")
+	fs.DurationVar(&cfg.LinearizabilityTimeout, "linearizability-timeout", cfg.LinearizabilityTimeout, "give up the linearizability check after this long, 0 for no limit")
+}
+
+// kindFlags selects the workload run against the backend, and holds the
+// settings of the workloads other than the register one.
+type kindFlags struct {
+	kind       string
+	bank       workload.BankConfig
+	isolation  workload.IsolationConfig
+	listAppend workload.ListAppendConfig
+}
+
+func registerKindFlags(fs *flag.FlagSet) *kindFlags {
+	k := &kindFlags{
+		kind:       "register",
+		bank:       workload.DefaultBankConfig(),
+		isolation:  workload.DefaultIsolationConfig(),
+		listAppend: workload.DefaultListAppendConfig(),
+	}
+	fs.StringVar(&k.kind, "workload", k.kind, "workload to run: register (writes and checks keys), bank (transfers between accounts, tidb and mariadb), isolation (provokes isolation anomalies, tidb and mariadb), list-append (transactions appending to lists, tidb, mariadb and mongodb) or set (adds to a grow-only set, every backend)")
+	fs.IntVar(&k.bank.Accounts, "accounts", k.bank.Accounts, "number of bank accounts")
+	fs.IntVar(&k.bank.Balance, "balance", k.bank.Balance, "balance every bank account is opened with")
+	fs.IntVar(&k.bank.MaxTransfer, "max-transfer", k.bank.MaxTransfer, "most a bank transfer moves")
+	fs.DurationVar(&k.bank.ReadInterval, "read-interval", k.bank.ReadInterval, "pause between the snapshot reads checking the bank balances")
+	fs.Var(&isolationLevelFlag{cfg: &k.isolation}, "isolation-level", "isolation level of the isolation suite: read-uncommitted, read-committed, repeatable-read or serializable (default repeatable-read)")
+	fs.Var(&anomaliesFlag{cfg: &k.isolation}, "anomalies", "comma-separated anomalies the isolation suite provokes in turn (default "+strings.Join(workload.IsolationAnomalies, ",")+")")
+	fs.IntVar(&k.listAppend.Keys, "list-keys", k.listAppend.Keys, "number of lists the list-append transactions pick from at a time")
+	fs.IntVar(&k.listAppend.MaxWritesPerKey, "max-writes-per-key", k.listAppend.MaxWritesPerKey, "appends a list gets on average before it is replaced by a fresh one")
+	fs.IntVar(&k.listAppend.MaxTxnLength, "max-txn-length", k.listAppend.MaxTxnLength, "most reads and appends a list-append transaction runs")
+	return k
+}
+
+// runner returns the selected workload on driver.
+func (k *kindFlags) runner(backend string, driver workload.Driver, cfg workload.Config) (interface{ Run(context.Context) error }, error) {
+	switch k.kind {
+	case "register":
+		return workload.New(driver, cfg), nil
+	case "bank":
+		bank, ok := driver.(workload.BankDriver)
+		if !ok {
+			return nil, fmt.Errorf("backend %s does not support the bank workload", backend)
+		}
+		return workload.NewBank(bank, cfg, k.bank), nil
+	case "isolation":
+		isolation, ok := driver.(workload.IsolationDriver)
+		if !ok {
+			return nil, fmt.Errorf("backend %s does not support the isolation workload", backend)
+		}
+		return workload.NewIsolation(isolation, cfg, k.isolation), nil
+	case "list-append":
+		lists, ok := driver.(workload.ListAppendDriver)
+		if !ok {
+			return nil, fmt.Errorf("backend %s does not support the list-append workload", backend)
+		}
+		return workload.NewListAppend(lists, cfg, k.listAppend), nil
+	case "set":
+		set, ok := driver.(workload.SetDriver)
+		if !ok {
+			return nil, fmt.Errorf("backend %s does not support the set workload", backend)
+		}
+		return workload.NewSet(set, cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown workload %q", k.kind)
+	}
+}
+
+// resolveInstance fills in the client id and instance index left to their
+// defaults: the pod name or hostname, and with several instances the ordinal
+// a StatefulSet appends to its pod names, e.g. 2 for writer-2. A single
+// instance is instance 0, whatever its hostname ends in.
+func resolveInstance(cfg *workload.Config) error {
+	if cfg.Client == "" {
+		cfg.Client = os.Getenv("POD_NAME")
+	}
+	if cfg.Client == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("client id: %w", err)
+		}
+		cfg.Client = hostname
+	}
+	if cfg.Instance >= 0 {
+		return nil
+	}
+	cfg.Instance = 0
+	if cfg.Instances <= 1 {
+		return nil
+	}
+	if i := strings.LastIndex(cfg.Client, "-"); i >= 0 {
+		if ordinal, err := strconv.Atoi(cfg.Client[i+1:]); err == nil {
+			cfg.Instance = ordinal
+		}
+	}
+	return nil
+}
+
+// outputFlags selects where and in which format the run is reported.
+type outputFlags struct {
+	format      string
+	path        string
+	history     string
+	coordinator string
+}
+
+func registerOutputFlags(fs *flag.FlagSet) *outputFlags {
+	o := &outputFlags{format: "text"}
+	fs.StringVar(&o.format, "output", o.format, "report format: text or json (one JSON object per line)")
+	fs.StringVar(&o.path, "output-file", o.path, "write the report to this file instead of stdout")
+	fs.StringVar(&o.history, "history", o.history, "record every operation to this file for offline analysis")
+	fs.StringVar(&o.coordinator, "coordinator", o.coordinator, "also push the results to the coordinator at this URL, e.g. http://coordinator:8080")
+	return o
+}
+
+// reporter opens the configured output. The returned close function flushes
+// and closes the output file, if any.
+func (o *outputFlags) reporter() (workload.Reporter, func() error, error) {
+	var w io.Writer = os.Stdout
+	closeOutput := func() error { return nil }
+	if o.path != "" {
+		f, err := os.Create(o.path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening output: %w", err)
+		}
+		w = f
+		closeOutput = f.Close
+	}
+
+	switch o.format {
+	case "text":
+		return workload.NewTextReporter(w), closeOutput, nil
+	case "json":
+		return workload.NewJSONReporter(w), closeOutput, nil
+	default:
+		closeOutput()
+		return nil, nil, fmt.Errorf("unknown output format %q", o.format)
+	}
+}
+
+// recorder opens the configured history file, or returns nil if recording
+// is disabled. A resumed run appends to the history of the previous one.
+func (o *outputFlags) recorder(resume bool) (*history.Recorder, error) {
+	if o.history == "" {
+		return nil, nil
+	}
+	open := history.NewRecorder
+	if resume {
+		open = history.OpenRecorder
+	}
+	r, err := open(o.history)
+	if err != nil {
+		return nil, fmt.Errorf("opening history: %w", err)
+	}
+	return r, nil
+}
+
+func registerMetricsFlag(fs *flag.FlagSet) *string {
+	return fs.String("metrics-addr", workload.GetEnvWithDefault("METRICS_ADDR", ":9090"), "address of the Prometheus /metrics endpoint, empty to disable (env METRICS_ADDR)")
+}
+
+// scheduleFlag parses a schedule spec into the Schedule of cfg.
+type scheduleFlag struct {
+	cfg  *workload.Config
+	spec string
+}
+
+func (f *scheduleFlag) String() string {
+	return f.spec
+}
+
+func (f *scheduleFlag) Set(spec string) error {
+	schedule, err := workload.ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	f.spec = spec
+	f.cfg.Schedule = schedule
+	return nil
+}
+
+// profileFlag applies a YCSB profile to cfg. It is a preset: the other
+// settings of the same layer override it.
+type profileFlag struct {
+	cfg  *workload.Config
+	name string
+}
+
+func (f *profileFlag) String() string {
+	return f.name
+}
+
+func (f *profileFlag) Set(name string) error {
+	profile, err := workload.LookupProfile(name)
+	if err != nil {
+		return err
+	}
+	f.name = profile.Name
+	profile.Apply(f.cfg)
+	return nil
+}
+
+func (f *profileFlag) preset() {}
+
+// isolationLevelFlag parses an isolation level into the Level of cfg.
+type isolationLevelFlag struct {
+	cfg *workload.IsolationConfig
+}
+
+func (f *isolationLevelFlag) String() string {
+	if f.cfg == nil {
+		return ""
+	}
+	return string(f.cfg.Level)
+}
+
+func (f *isolationLevelFlag) Set(spec string) error {
+	level, err := workload.ParseIsolationLevel(spec)
+	if err != nil {
+		return err
+	}
+	f.cfg.Level = level
+	return nil
+}
+
+// anomaliesFlag parses a comma-separated list of anomalies into the
+// Anomalies of cfg.
+type anomaliesFlag struct {
+	cfg *workload.IsolationConfig
+}
+
+func (f *anomaliesFlag) String() string {
+	if f.cfg == nil {
+		return ""
+	}
+	return strings.Join(f.cfg.Anomalies, ",")
+}
+
+func (f *anomaliesFlag) Set(value string) error {
+	var anomalies []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			anomalies = append(anomalies, part)
+		}
+	}
+	f.cfg.Anomalies = anomalies
+	return (workload.IsolationConfig{Level: f.cfg.Level, Anomalies: anomalies}).Validate()
+}
+
+// probeFlag parses a probe spec into the Probe of cfg.
+type probeFlag struct {
+	cfg  *workload.Config
+	spec string
+}
+
+func (f *probeFlag) String() string {
+	return f.spec
+}
+
+func (f *probeFlag) Set(spec string) error {
+	probe, err := workload.ParseProbe(spec)
+	if err != nil {
+		return err
+	}
+	f.spec = spec
+	f.cfg.Probe = probe
+	return nil
+}
+
+// durationsFlag is a comma-separated list of durations.
+type durationsFlag []time.Duration
+
+func (f *durationsFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, d := range *f {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *durationsFlag) Set(value string) error {
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return err
+		}
+		durations = append(durations, d)
+	}
+	*f = durations
+	return nil
+}
+
+func run(name string, args []string) error {
+	b, ok := backends[name]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown backend %q", name)
+	}
+
+	fs := flag.NewFlagSet("workloads run "+name, flag.ExitOnError)
+	cfg := workload.DefaultConfig()
+	cfg.Instance = -1
+	connect := b(fs, &cfg)
+	metricsAddr := registerMetricsFlag(fs)
+	wfs := flag.NewFlagSet("", flag.ContinueOnError)
+	registerWorkloadFlags(wfs, &cfg)
+	kind := registerKindFlags(wfs)
+	output := registerOutputFlags(wfs)
+	if err := parseLayered(fs, wfs, args); err != nil {
+		return err
+	}
+
+	reporter, closeOutput, err := output.reporter()
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	// Only the register workload saves and resumes from checkpoints.
+	if cfg.Checkpoint != "" && kind.kind != "register" {
+		return fmt.Errorf("-checkpoint does not apply to the %s workload", kind.kind)
+	}
+	if cfg.Checkpoint != "" {
+		if cfg.Resume, err = workload.LoadCheckpoint(cfg.Checkpoint); err != nil {
+			return err
+		}
+	}
+	recorder, err := output.recorder(cfg.Resume != nil)
+	if err != nil {
+		return err
+	}
+	if recorder != nil {
+		defer recorder.Close()
+		cfg.History = recorder
+	}
+
+	cfg.Backend = name
+	if err := resolveInstance(&cfg); err != nil {
+		return err
+	}
+	cfg.Reporter = reporter
+	if output.coordinator != "" {
+		pusher := coordinator.NewPusher(output.coordinator, cfg.Client, time.Second)
+		defer pusher.Close()
+		cfg.Reporter = workload.MultiReporter{reporter, pusher}
+	}
+	workload.ServeMetrics(*metricsAddr)
+
+	// A signal while waiting for the host or connecting ends the run too.
+	ctx, stop := shutdownContext()
+	defer stop()
+	driver, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer driver.Close()
+
+	r, err := kind.runner(name, driver, cfg)
+	if err != nil {
+		return err
+	}
+	return r.Run(ctx)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/sqldb"
+)
+
+func registerSQLFlags(fs *flag.FlagSet, cfg *sqldb.Config, envPrefix, defaultPort, dbNameEnv string) {
+	fs.StringVar(&cfg.Host, "host", workload.GetEnvWithDefault(envPrefix+"_HOST", "127.0.0.1"), "database host (env "+envPrefix+"_HOST)")
+	fs.StringVar(&cfg.Port, "port", workload.GetEnvWithDefault(envPrefix+"_PORT", defaultPort), "database port (env "+envPrefix+"_PORT)")
+	fs.StringVar(&cfg.User, "user", workload.GetEnvWithDefault(envPrefix+"_USER", "root"), "database user (env "+envPrefix+"_USER)")
+	fs.StringVar(&cfg.Password, "password", workload.GetEnvWithDefault(envPrefix+"_PASSWORD", ""), "database password (env "+envPrefix+"_PASSWORD)")
+	fs.StringVar(&cfg.DBName, "database", workload.GetEnvWithDefault(dbNameEnv, "test"), "database name (env "+dbNameEnv+")")
+	fs.StringVar(&cfg.UseSSL, "tls", workload.GetEnvWithDefault("USE_SSL", "false"), "tls mode of the connection (env USE_SSL)")
+}
+
+func connectSQL(cfg *sqldb.Config, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	return func(ctx context.Context) (workload.Driver, error) {
+		if err := workload.WaitForHost(ctx, cfg.Host); err != nil {
+			return nil, err
+		}
+		cfg.Record = wcfg.Record
+		return sqldb.NewDriver(*cfg)
+	}
+}
+
+func tidbBackend(fs *flag.FlagSet, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	cfg := &sqldb.Config{}
+	registerSQLFlags(fs, cfg, "TIDB", "4000", "TIDB_DB_NAME")
+	return connectSQL(cfg, wcfg)
+}
+
+func mariadbBackend(fs *flag.FlagSet, wcfg *workload.Config) func(ctx context.Context) (workload.Driver, error) {
+	cfg := &sqldb.Config{}
+	registerSQLFlags(fs, cfg, "MARIADB", "4000", "MARIADB_DATABASE")
+	return connectSQL(cfg, wcfg)
+}
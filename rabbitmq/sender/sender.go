@@ -1,65 +0,0 @@
-package main
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"os"
-	"time"
-
-	amqp "github.com/rabbitmq/amqp091-go"
-)
-
-func failOnError(err error, msg string) {
-	if err != nil {
-		log.Panicf("%s: %s", msg, err)
-	}
-}
-
-func sendMessage(ctx context.Context, ch *amqp.Channel, q amqp.Queue, body string) {
-	err := ch.PublishWithContext(ctx,
-		"",     // exchange
-		q.Name, // routing key
-		false,  // mandatory
-		false,  // immediate
-		amqp.Publishing{
-			DeliveryMode: amqp.Persistent,
-			ContentType:  "text/plain",
-			Body:         []byte(body),
-		})
-	failOnError(err, "Failed to publish a message")
-	log.Printf(" [x] Sent %s\n", body)
-}
-
-func main() {
-	username := os.Getenv("SECRET_USERNAME")
-	password := os.Getenv("SECRET_PASSWORD")
-	host := os.Getenv("SECRET_HOST")
-	url := fmt.Sprintf("amqp://%s:%s@%s:5672/", username, password, host)
-	conn, err := amqp.Dial(url)
-	failOnError(err, "Failed to connect to RabbitMQ")
-	defer conn.Close()
-
-	ch, err := conn.Channel()
-	failOnError(err, "Failed to open a channel")
-	defer ch.Close()
-
-	q, err := ch.QueueDeclare(
-		"task_queue", // name
-		true,         // durable
-		false,        // delete when unused
-		false,        // exclusive
-		false,        // no-wait
-		nil,          // arguments
-	)
-	failOnError(err, "Failed to declare a queue")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	sequence := 0
-	for {
-		sendMessage(ctx, ch, q, fmt.Sprint(sequence))
-		time.Sleep(1 * time.Second)
-		sequence++
-	}
-}
@@ -0,0 +1,294 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// ErrInsufficientFunds is returned by BankDriver.Transfer when the source
+// account holds less than the amount. The transfer did not apply.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+const (
+	// AnomalyWrongTotal is a snapshot whose balances do not add up to the
+	// balance the accounts were opened with.
+	AnomalyWrongTotal = "wrong-total"
+	// AnomalyNegativeBalance is a snapshot with an account below zero.
+	AnomalyNegativeBalance = "negative-balance"
+)
+
+// BankDriver is implemented by backends with transactions that can run the
+// bank workload.
+type BankDriver interface {
+	Driver
+	// SetupAccounts (re)creates accounts accounts, numbered from 0, holding
+	// balance each.
+	SetupAccounts(ctx context.Context, accounts, balance int) error
+	// Transfer moves amount from one account to another in one
+	// transaction. It returns ErrInsufficientFunds, without moving
+	// anything, if from holds less than amount.
+	Transfer(ctx context.Context, from, to, amount int) error
+	// Balances returns the balance of every account, read from one
+	// snapshot.
+	Balances(ctx context.Context) (map[int]int, error)
+}
+
+// BankConfig configures the bank workload on top of the Config shared with
+// the register workload.
+type BankConfig struct {
+	// Accounts is the number of accounts, Balance what each is opened with
+	// and MaxTransfer the most a transfer moves.
+	Accounts    int
+	Balance     int
+	MaxTransfer int
+	// ReadInterval is the pause between the snapshot reads that check the
+	// balances.
+	ReadInterval time.Duration
+}
+
+func DefaultBankConfig() BankConfig {
+	return BankConfig{
+		Accounts:     10,
+		Balance:      100,
+		MaxTransfer:  5,
+		ReadInterval: time.Second,
+	}
+}
+
+func (c BankConfig) Validate() error {
+	if c.Accounts < 2 {
+		return fmt.Errorf("a bank needs at least 2 accounts, got %d", c.Accounts)
+	}
+	if c.Balance < 0 {
+		return fmt.Errorf("balance must not be negative, got %d", c.Balance)
+	}
+	if c.MaxTransfer <= 0 {
+		return fmt.Errorf("max transfer must be positive, got %d", c.MaxTransfer)
+	}
+	if c.ReadInterval <= 0 {
+		return fmt.Errorf("read interval must be positive, got %s", c.ReadInterval)
+	}
+	return nil
+}
+
+// total is the sum of the balances of every account.
+func (c BankConfig) total() int {
+	return c.Accounts * c.Balance
+}
+
+// BankReport summarizes the snapshot reads of the bank workload.
+type BankReport struct {
+	// Total is the sum of the balances every snapshot must show.
+	Total     int `json:"total"`
+	Snapshots int `json:"snapshots"`
+	// WrongTotals and Negative count the snapshots that broke the
+	// invariants.
+	WrongTotals int `json:"wrong_totals"`
+	Negative    int `json:"negative"`
+	// Healthy tells whether the probe passed, after WaitMs, before the
+	// final snapshot once the transfers stopped, and FinalTotal is the
+	// total it read, nil if the balances could not be read.
+	Healthy    bool    `json:"healthy"`
+	WaitMs     float64 `json:"wait_ms"`
+	FinalTotal *int    `json:"final_total,omitempty"`
+}
+
+// Bank drives transfers between accounts at the scheduled rate, and keeps
+// checking with snapshot reads that no money is created or destroyed and no
+// account is overdrawn. Breaking either means a transaction was applied
+// partially or a read saw a state no serial order of transfers produces.
+type Bank struct {
+	// w holds the settings, stats and reporting shared with the register
+	// workload.
+	w      *Workload
+	driver BankDriver
+	cfg    BankConfig
+
+	mu     sync.Mutex
+	report BankReport
+}
+
+// NewBank returns a bank workload. Of cfg, it uses the settings of the load,
+// its reporting and thresholds, the probe before the final snapshot and the
+// instance; the settings of the keys and their checks do not apply.
+func NewBank(driver BankDriver, cfg Config, bank BankConfig) *Bank {
+	return &Bank{
+		w:      New(driver, cfg),
+		driver: driver,
+		cfg:    bank,
+		report: BankReport{Total: bank.total()},
+	}
+}
+
+// transfer returns the accounts and amount of the transfer of sequence,
+// picked from the seed.
+func (b *Bank) transfer(sequence int) (from, to, amount int) {
+	h := random(b.w.cfg.Seed, sequence)
+	from = pick(h, b.cfg.Accounts)
+	to = (from + 1 + pick(mix(h), b.cfg.Accounts-1)) % b.cfg.Accounts
+	amount = 1 + pick(mix(mix(h)), b.cfg.MaxTransfer)
+	return from, to, amount
+}
+
+func (b *Bank) Run(ctx context.Context) error {
+	w := b.w
+	if err := w.cfg.Validate(); err != nil {
+		return err
+	}
+	if err := b.cfg.Validate(); err != nil {
+		return err
+	}
+	// Only the first instance opens the accounts for all of them.
+	if w.cfg.Instance == 0 {
+		if err := b.driver.SetupAccounts(ctx, b.cfg.Accounts, b.cfg.Balance); err != nil {
+			return fmt.Errorf("setup: %w", err)
+		}
+	}
+
+	start, dropped, err := w.drive(ctx, loop{do: b.do, background: []func(context.Context){b.check}})
+	if err != nil {
+		return err
+	}
+	// The final snapshot outlives ctx, like the transfers.
+	b.final(context.WithoutCancel(ctx))
+
+	summary := w.tally.summary(Summary{
+		Backend: w.cfg.Backend,
+		Client:  w.cfg.Client,
+		Start:   start,
+		End:     time.Now(),
+		Dropped: dropped,
+		Bank:    b.summary(),
+	})
+	return w.finish(summary)
+}
+
+// do runs the transfer of o.
+func (b *Bank) do(ctx context.Context, _ int, o op) Result {
+	w := b.w
+	from, to, amount := b.transfer(o.sequence)
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	err := b.driver.Transfer(ctx, from, to, amount)
+	w.measure(OpTransfer, from, &amount, start, time.Now(), classify(b.driver, err), err)
+	// An overdrawn account was answered, the backend was available.
+	if rejected(OpTransfer, err) {
+		err = nil
+	}
+	return Result{err: err, ts: o.ts}
+}
+
+// final waits for the backend to become healthy and takes the last
+// snapshot, retrying failed reads a few times.
+func (b *Bank) final(ctx context.Context) {
+	healthy, waited := b.w.waitHealthy(ctx)
+	b.mu.Lock()
+	b.report.Healthy = healthy
+	b.report.WaitMs = durationToMs(waited)
+	b.mu.Unlock()
+	for attempt := 0; attempt < finalReadAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(probeInterval)
+		}
+		if total, err := b.snapshot(ctx); err == nil {
+			b.mu.Lock()
+			b.report.FinalTotal = &total
+			b.mu.Unlock()
+			return
+		}
+	}
+}
+
+// check takes a snapshot every ReadInterval until ctx is cancelled.
+func (b *Bank) check(ctx context.Context) {
+	ticker := time.NewTicker(b.cfg.ReadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.snapshot(ctx)
+		}
+	}
+}
+
+// snapshot reads the balances, reports the invariants they break and returns
+// their total.
+func (b *Bank) snapshot(ctx context.Context) (int, error) {
+	w := b.w
+	readCtx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	balances, err := b.driver.Balances(readCtx)
+	if ctx.Err() != nil {
+		// The run is over, the read was cut short.
+		return 0, ctx.Err()
+	}
+	outcome := history.OK
+	if err != nil {
+		outcome = history.Fail
+	}
+	w.measure(OpBalances, 0, nil, start, time.Now(), outcome, err)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	var negative []int
+	for account, balance := range balances {
+		total += balance
+		if balance < 0 {
+			negative = append(negative, account)
+		}
+	}
+	sort.Ints(negative)
+	b.mu.Lock()
+	b.report.Snapshots++
+	if total != b.cfg.total() {
+		b.report.WrongTotals++
+	}
+	if len(negative) > 0 {
+		b.report.Negative++
+	}
+	b.mu.Unlock()
+
+	if total != b.cfg.total() {
+		b.anomaly(AnomalyWrongTotal, -1, b.cfg.total(), &total,
+			fmt.Sprintf("%d accounts hold %d in total, %d expected", len(balances), total, b.cfg.total()))
+	}
+	for _, account := range negative {
+		balance := balances[account]
+		b.anomaly(AnomalyNegativeBalance, account, 0, &balance,
+			fmt.Sprintf("account %d holds %d", account, balance))
+	}
+	return total, nil
+}
+
+func (b *Bank) anomaly(kind string, key, expected int, actual *int, message string) {
+	w := b.w
+	inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+	w.tally.anomaly(kind)
+	w.reporter.Anomaly(AnomalyEvent{
+		Time:     time.Now(),
+		Kind:     kind,
+		Key:      key,
+		Expected: expected,
+		Actual:   actual,
+		Message:  message,
+	})
+}
+
+func (b *Bank) summary() *BankReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	report := b.report
+	return &report
+}
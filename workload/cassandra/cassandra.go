@@ -0,0 +1,159 @@
+// Package cassandra implements the workload driver for Cassandra.
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+type Config struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// Record switches the driver from the test.player table to the shared
+	// record schema.
+	Record *workload.Record
+}
+
+// Driver writes the workload into the test.player table, one row per key
+// with the value stored in `coins`, or into the table of the Record schema.
+type Driver struct {
+	session *gocql.Session
+	table   table
+}
+
+var (
+	_ workload.Driver          = &Driver{}
+	_ workload.Inserter        = &Driver{}
+	_ workload.Updater         = &Driver{}
+	_ workload.Deleter         = &Driver{}
+	_ workload.Scanner         = &Driver{}
+	_ workload.ErrorClassifier = &Driver{}
+	_ workload.Pinger          = &Driver{}
+)
+
+func NewDriver(cfg Config) (*Driver, error) {
+	cluster := gocql.NewCluster(cfg.Host)
+	cluster.Port = cfg.Port
+	cluster.Consistency = gocql.Quorum
+	cluster.ProtoVersion = 4
+	cluster.ConnectTimeout = time.Second * 1
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username:              cfg.User,
+		Password:              cfg.Password,
+		AllowedAuthenticators: []string{"org.apache.cassandra.auth.PasswordAuthenticator"},
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, err
+	}
+	t := playerTable
+	if cfg.Record != nil {
+		t = recordTable(*cfg.Record)
+	}
+	return &Driver{session: session, table: t}, nil
+}
+
+func (d *Driver) Setup(ctx context.Context) error {
+	if err := d.session.Query("ALTER KEYSPACE system_auth WITH replication = {'class': 'NetworkTopologyStrategy', 'replication_factor': 3};").WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	if err := d.session.Query("CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class': 'NetworkTopologyStrategy', 'replication_factor': 3}").WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	if err := d.session.Query(d.table.create).WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Write upserts the row: in Cassandra, both INSERT and UPDATE do.
+func (d *Driver) Write(ctx context.Context, key, value int) error {
+	return d.session.Query(d.table.upsert, d.table.set(key, value)...).WithContext(ctx).Exec()
+}
+
+// Insert and Update are lightweight transactions, since plain writes do not
+// check whether the row exists.
+func (d *Driver) Insert(ctx context.Context, key, value int) error {
+	applied, err := d.session.Query(d.table.insert, d.table.row(key, value)...).
+		WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	if err == nil && !applied {
+		return workload.ErrExists
+	}
+	return err
+}
+
+func (d *Driver) Update(ctx context.Context, key, value int) error {
+	applied, err := d.session.Query(d.table.update, d.table.set(key, value)...).
+		WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	if err == nil && !applied {
+		return workload.ErrNotFound
+	}
+	return err
+}
+
+func (d *Driver) Delete(ctx context.Context, key int) error {
+	return d.session.Query(d.table.delete, d.table.id(key)).
+		WithContext(ctx).Exec()
+}
+
+// Scan reads the keys of the range by primary key: the rows are spread over
+// the ring by the hash of id, so there is no range of ids to scan.
+func (d *Driver) Scan(ctx context.Context, from, to int) ([]workload.KeyValue, error) {
+	iter := d.session.Query(d.table.scan, d.table.ids(from, to)).
+		WithContext(ctx).Iter()
+	var kvs []workload.KeyValue
+	var coins int
+	dest := d.table.scanner(&coins)
+	for iter.Scan(dest...) {
+		key, err := d.table.key(dest[0])
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		kvs = append(kvs, workload.KeyValue{Key: key, Value: coins})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+func (d *Driver) Read(ctx context.Context, key int) (int, error) {
+	var coins int
+	err := d.session.Query(d.table.get, d.table.id(key)).
+		WithContext(ctx).Scan(d.table.scanner(&coins)...)
+	if errors.Is(err, gocql.ErrNotFound) {
+		return 0, workload.ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return coins, nil
+}
+
+// Ping queries the local table of the node the session is connected to.
+func (d *Driver) Ping(ctx context.Context) error {
+	return d.session.Query("SELECT now() FROM system.local").WithContext(ctx).Exec()
+}
+
+// Indeterminate reports whether a write may have been applied despite err.
+// A write timeout means too few replicas acknowledged in time, not that the
+// write was rolled back on the ones that did.
+func (d *Driver) Indeterminate(err error) bool {
+	var timeout *gocql.RequestErrWriteTimeout
+	return errors.As(err, &timeout) || errors.Is(err, gocql.ErrTimeoutNoResponse) ||
+		errors.Is(err, gocql.ErrConnectionClosed)
+}
+
+func (d *Driver) Close() error {
+	d.session.Close()
+	return nil
+}
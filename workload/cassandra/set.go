@@ -0,0 +1,44 @@
+package cassandra
+
+import (
+	"context"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+const (
+	createSetCQL = "CREATE TABLE IF NOT EXISTS test.set_elements (set_id int, element int, PRIMARY KEY (set_id, element))"
+	clearSetCQL  = "DELETE FROM test.set_elements WHERE set_id = ?"
+	addSetCQL    = "INSERT INTO test.set_elements (set_id, element) VALUES (?, ?)"
+	getSetCQL    = "SELECT element FROM test.set_elements WHERE set_id = ?"
+)
+
+var _ workload.SetDriver = &Driver{}
+
+// SetupSet creates the test.set_elements table, with one partition per set,
+// and deletes the partition of set.
+func (d *Driver) SetupSet(ctx context.Context, set int) error {
+	if err := d.session.Query("CREATE KEYSPACE IF NOT EXISTS test WITH REPLICATION = {'class': 'NetworkTopologyStrategy', 'replication_factor': 3}").WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	if err := d.session.Query(createSetCQL).WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	return d.session.Query(clearSetCQL, set).WithContext(ctx).Exec()
+}
+
+// Add inserts element as a row of the partition of set.
+func (d *Driver) Add(ctx context.Context, set, element int) error {
+	return d.session.Query(addSetCQL, set, element).WithContext(ctx).Exec()
+}
+
+// ReadSet reads the whole partition of set, a page at a time.
+func (d *Driver) ReadSet(ctx context.Context, set int) ([]int, error) {
+	iter := d.session.Query(getSetCQL, set).WithContext(ctx).Iter()
+	var elements []int
+	var element int
+	for iter.Scan(&element) {
+		elements = append(elements, element)
+	}
+	return elements, iter.Close()
+}
@@ -0,0 +1,118 @@
+package cassandra
+
+import (
+	"fmt"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+// table holds the statements of the table a Driver writes to. The rows of
+// insert take the id, the value and the fields, update and upsert take the
+// value, the fields and the id, and get and scan return the id, the value
+// and the fields.
+type table struct {
+	create                                    string
+	get, insert, upsert, update, delete, scan string
+	// id is the primary key of key and key its inverse.
+	id  func(key int) any
+	key func(id any) (int, error)
+	// fields returns the columns written besides the value.
+	fields func(key, value int) []any
+	// columns is the number of columns returned besides the id and value.
+	columns int
+}
+
+var playerTable = table{
+	create: "CREATE TABLE IF NOT EXISTS test.player (id int PRIMARY KEY, coins int)",
+	get:    "SELECT id, coins FROM test.player WHERE id = ?",
+	insert: "INSERT INTO test.player (id, coins) VALUES (?, ?) IF NOT EXISTS",
+	upsert: "UPDATE test.player SET coins = ? WHERE id = ?",
+	update: "UPDATE test.player SET coins = ? WHERE id = ? IF EXISTS",
+	delete: "DELETE FROM test.player WHERE id = ?",
+	scan:   "SELECT id, coins FROM test.player WHERE id IN ?",
+	id:     func(key int) any { return key },
+	key: func(id any) (int, error) {
+		key, ok := id.(*int)
+		if !ok {
+			return 0, fmt.Errorf("id is %T", id)
+		}
+		return *key, nil
+	},
+	fields: func(key, value int) []any { return nil },
+}
+
+// recordTable is the table of the Record schema shared with the other
+// backends.
+func recordTable(r workload.Record) table {
+	t := "test." + workload.RecordTable
+	columns := "ycsb_key, value"
+	placeholders := "?, ?"
+	sets := "value = ?"
+	definitions := "ycsb_key text PRIMARY KEY, value int"
+	for i := 0; i < r.Fields; i++ {
+		name := r.FieldName(i)
+		columns += ", " + name
+		placeholders += ", ?"
+		sets += fmt.Sprintf(", %s = ?", name)
+		definitions += fmt.Sprintf(", %s text", name)
+	}
+	return table{
+		create: fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", t, definitions),
+		get:    fmt.Sprintf("SELECT %s FROM %s WHERE ycsb_key = ?", columns, t),
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) IF NOT EXISTS", t, columns, placeholders),
+		upsert: fmt.Sprintf("UPDATE %s SET %s WHERE ycsb_key = ?", t, sets),
+		update: fmt.Sprintf("UPDATE %s SET %s WHERE ycsb_key = ? IF EXISTS", t, sets),
+		delete: fmt.Sprintf("DELETE FROM %s WHERE ycsb_key = ?", t),
+		scan:   fmt.Sprintf("SELECT %s FROM %s WHERE ycsb_key IN ?", columns, t),
+		id:     func(key int) any { return r.Key(key) },
+		key: func(id any) (int, error) {
+			key, ok := id.(*string)
+			if !ok {
+				return 0, fmt.Errorf("id is %T", id)
+			}
+			return r.ParseKey(*key)
+		},
+		fields: func(key, value int) []any {
+			fields := make([]any, r.Fields)
+			for i := range fields {
+				fields[i] = r.Field(key, value, i)
+			}
+			return fields
+		},
+		columns: r.Fields,
+	}
+}
+
+// row returns the arguments of insert.
+func (t table) row(key, value int) []any {
+	return append([]any{t.id(key), value}, t.fields(key, value)...)
+}
+
+// set returns the arguments of update and upsert.
+func (t table) set(key, value int) []any {
+	return append(append([]any{value}, t.fields(key, value)...), t.id(key))
+}
+
+// ids returns the ids of the keys in [from, to).
+func (t table) ids(from, to int) []any {
+	ids := make([]any, 0, to-from)
+	for key := from; key < to; key++ {
+		ids = append(ids, t.id(key))
+	}
+	return ids
+}
+
+// scanner returns the destinations of a row of get or scan: the id, the
+// value and the columns, which are discarded. The id is a pointer to the
+// type of the ids.
+func (t table) scanner(value *int) []any {
+	var id any = new(int)
+	if _, ok := t.id(0).(string); ok {
+		id = new(string)
+	}
+	dest := []any{id, value}
+	for i := 0; i < t.columns; i++ {
+		dest = append(dest, new(string))
+	}
+	return dest
+}
@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// Names of the checkers Analyze can run.
+const (
+	CheckLinearizability = "linearizability"
+	CheckLostWrites      = "lost-writes"
+	CheckMonotonicReads  = "monotonic-reads"
+	CheckAvailability    = "availability"
+	CheckListAppend      = "list-append"
+	CheckSet             = "set"
+)
+
+// AllCheckers lists every checker Analyze can run.
+var AllCheckers = []string{CheckLinearizability, CheckLostWrites, CheckMonotonicReads, CheckAvailability, CheckListAppend, CheckSet}
+
+// Options selects and tunes the checkers Analyze runs.
+type Options struct {
+	// Checkers names the checkers to run. Empty runs all of them.
+	Checkers []string
+	// LinearizabilityTimeout bounds the linearizability check, 0 for no
+	// limit.
+	LinearizabilityTimeout time.Duration
+	// BucketSize is the resolution of the availability intervals.
+	BucketSize time.Duration
+}
+
+// Analysis holds the results of every checker run over one history.
+type Analysis struct {
+	Ops          int           `json:"ops"`
+	Valid        Validity      `json:"valid"`
+	Results      []Result      `json:"results"`
+	Availability *Availability `json:"availability,omitempty"`
+}
+
+// Analyze runs the selected checkers over ops. The analysis is invalid if
+// any checker found an anomaly, and unknown if none did but one gave up.
+func Analyze(ops []history.Op, opts Options) (Analysis, error) {
+	checkers := opts.Checkers
+	if len(checkers) == 0 {
+		checkers = AllCheckers
+	}
+
+	analysis := Analysis{Ops: len(ops), Valid: Valid}
+	for _, name := range checkers {
+		var result Result
+		switch name {
+		case CheckLinearizability:
+			result = Linearizable(ops, opts.LinearizabilityTimeout)
+		case CheckLostWrites:
+			result = LostWrites(ops)
+		case CheckMonotonicReads:
+			result = MonotonicReads(ops)
+		case CheckListAppend:
+			result = ListAppend(ops)
+		case CheckSet:
+			result = Set(ops)
+		case CheckAvailability:
+			if opts.BucketSize <= 0 {
+				return Analysis{}, fmt.Errorf("bucket size must be positive, got %s", opts.BucketSize)
+			}
+			availability := Availabilities(ops, opts.BucketSize)
+			analysis.Availability = &availability
+			continue
+		default:
+			return Analysis{}, fmt.Errorf("unknown checker %q", name)
+		}
+		analysis.Results = append(analysis.Results, result)
+		switch {
+		case result.Valid == Invalid:
+			analysis.Valid = Invalid
+		case result.Valid == Unknown && analysis.Valid == Valid:
+			analysis.Valid = Unknown
+		}
+	}
+	return analysis, nil
+}
@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+func TestAnalyze(t *testing.T) {
+	w, r := history.Write, history.Read
+	ok, info := history.OK, history.Info
+	valid := indexed(
+		op(0, w, 1, v(1), ok, 0, 10),
+		op(0, w, 1, v(2), info, 20, 30),
+		op(1, r, 1, v(2), ok, 40, 50),
+	)
+	lost := indexed(
+		op(0, w, 1, v(1), ok, 0, 10),
+		op(0, w, 1, v(2), ok, 20, 30),
+		op(1, r, 1, v(1), ok, 40, 50),
+	)
+	for _, tc := range []struct {
+		name     string
+		ops      []history.Op
+		checkers []string
+		want     Validity
+		results  int
+	}{
+		{name: "valid", ops: valid, want: Valid, results: len(AllCheckers) - 1},
+		{name: "lost write", ops: lost, want: Invalid, results: len(AllCheckers) - 1},
+		{name: "selected checkers", ops: lost, checkers: []string{CheckMonotonicReads, CheckAvailability}, want: Valid, results: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			analysis, err := Analyze(tc.ops, Options{Checkers: tc.checkers, BucketSize: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if analysis.Valid != tc.want {
+				t.Errorf("got %s, want %s: %+v", analysis.Valid, tc.want, analysis.Results)
+			}
+			if len(analysis.Results) != tc.results {
+				t.Errorf("got %d results, want %d", len(analysis.Results), tc.results)
+			}
+			if analysis.Ops != len(tc.ops) || analysis.Availability == nil {
+				t.Errorf("got %d ops and availability %v, want %d ops and an availability", analysis.Ops, analysis.Availability, len(tc.ops))
+			}
+		})
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	if _, err := Analyze(nil, Options{Checkers: []string{"serializability"}}); err == nil {
+		t.Error("unknown checker accepted")
+	}
+	if _, err := Analyze(nil, Options{Checkers: []string{CheckAvailability}}); err == nil {
+		t.Error("availability without a bucket size accepted")
+	}
+}
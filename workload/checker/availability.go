@@ -0,0 +1,82 @@
+package checker
+
+import (
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// Availability summarizes how many writes and deletes succeeded and when
+// none did.
+type Availability struct {
+	// Availability is the fraction of writes that succeeded.
+	Availability float64 `json:"availability"`
+	Writes       int     `json:"writes"`
+	OK           int     `json:"ok"`
+	// Unavailable are the intervals without a single successful write.
+	Unavailable []Interval `json:"unavailable,omitempty"`
+}
+
+// Interval is a span of buckets of the history.
+type Interval struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Writes int       `json:"writes"`
+}
+
+// Availabilities buckets writes by the time they were invoked and reports
+// the overall availability and every run of buckets, between the first and
+// the last write, in which no write succeeded. Writes with an unknown outcome
+// count as unsuccessful.
+func Availabilities(ops []history.Op, bucket time.Duration) Availability {
+	var a Availability
+	var writes []history.Op
+	for _, op := range ops {
+		if op.F == history.Read || op.F == history.ReadSet {
+			continue
+		}
+		writes = append(writes, op)
+		a.Writes++
+		if op.Outcome == history.OK {
+			a.OK++
+		}
+	}
+	if a.Writes == 0 {
+		return a
+	}
+	a.Availability = float64(a.OK) / float64(a.Writes)
+
+	origin := writes[0].Invoke.Truncate(bucket)
+	index := func(t time.Time) int { return int(t.Sub(origin) / bucket) }
+	n := index(writes[len(writes)-1].Invoke) + 1
+	total := make([]int, n)
+	ok := make([]int, n)
+	for _, op := range writes {
+		i := index(op.Invoke)
+		total[i]++
+		if op.Outcome == history.OK {
+			ok[i]++
+		}
+	}
+
+	var open *Interval
+	for i := 0; i < n; i++ {
+		if ok[i] > 0 {
+			if open != nil {
+				a.Unavailable = append(a.Unavailable, *open)
+				open = nil
+			}
+			continue
+		}
+		start := origin.Add(time.Duration(i) * bucket)
+		if open == nil {
+			open = &Interval{Start: start}
+		}
+		open.End = start.Add(bucket)
+		open.Writes += total[i]
+	}
+	if open != nil {
+		a.Unavailable = append(a.Unavailable, *open)
+	}
+	return a
+}
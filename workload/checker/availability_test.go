@@ -0,0 +1,69 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+func TestAvailabilities(t *testing.T) {
+	w, r := history.Write, history.Read
+	ok, fail, info := history.OK, history.Fail, history.Info
+	for _, tc := range []struct {
+		name string
+		ops  []history.Op
+		want Availability
+	}{
+		{
+			name: "no writes",
+			ops:  indexed(op(0, r, 1, v(1), ok, 0, 10)),
+			want: Availability{},
+		},
+		{
+			name: "always available",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, r, 1, nil, fail, 500, 510),
+				op(0, w, 1, v(2), ok, 1500, 1510),
+			),
+			want: Availability{Availability: 1, Writes: 2, OK: 2},
+		},
+		{
+			name: "outage",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), fail, 1000, 1010),
+				op(0, w, 1, v(3), info, 1500, 1510),
+				op(1, w, 1, v(4), fail, 2000, 2010),
+				op(0, w, 1, v(5), ok, 4000, 4010),
+			),
+			want: Availability{
+				Availability: 0.4,
+				Writes:       5,
+				OK:           2,
+				Unavailable:  []Interval{{Start: at(1000), End: at(4000), Writes: 3}},
+			},
+		},
+		{
+			name: "unavailable at the end",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), fail, 1000, 1010),
+			),
+			want: Availability{
+				Availability: 0.5,
+				Writes:       2,
+				OK:           1,
+				Unavailable:  []Interval{{Start: at(1000), End: at(2000), Writes: 1}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Availabilities(tc.ops, time.Second); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
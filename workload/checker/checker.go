@@ -0,0 +1,54 @@
+// Package checker analyzes recorded histories for correctness anomalies.
+//
+// Checkers expect the operations sorted by invocation time, as history.Load
+// returns them.
+package checker
+
+import (
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// Validity is the verdict of a checker.
+type Validity string
+
+const (
+	Valid   Validity = "valid"
+	Invalid Validity = "invalid"
+	// Unknown means the checker gave up, e.g. because it ran out of time.
+	Unknown Validity = "unknown"
+)
+
+// Result is the outcome of running one checker over a history.
+type Result struct {
+	Checker   string    `json:"checker"`
+	Valid     Validity  `json:"valid"`
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+	// Detail explains an unknown verdict.
+	Detail string `json:"detail,omitempty"`
+	// Recovered lists writes with an unknown outcome that a read showed
+	// were applied.
+	Recovered []history.Op `json:"recovered,omitempty"`
+}
+
+// Anomaly is one violation found by a checker, with the operations that
+// demonstrate it.
+type Anomaly struct {
+	Kind    string       `json:"kind"`
+	Key     int          `json:"key"`
+	Message string       `json:"message"`
+	Ops     []history.Op `json:"ops,omitempty"`
+}
+
+// byKey splits a history into the operations of every key, keeping their
+// order. Transactions and set operations are not operations on a register
+// and are left to ListAppend and Set.
+func byKey(ops []history.Op) map[int][]history.Op {
+	keys := map[int][]history.Op{}
+	for _, op := range ops {
+		if op.F != history.Write && op.F != history.Read && op.F != history.Delete {
+			continue
+		}
+		keys[op.Key] = append(keys[op.Key], op)
+	}
+	return keys
+}
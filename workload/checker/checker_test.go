@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// origin is the time the test histories start at.
+var origin = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(ms int) time.Time {
+	return origin.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// op returns an operation of process on key, invoked and completed at the
+// given milliseconds. A nil value is a read that found no value.
+func op(process int, f history.Func, key int, value *int, outcome history.Outcome, invoke, complete int) history.Op {
+	return history.Op{
+		Process:  process,
+		F:        f,
+		Key:      key,
+		Value:    value,
+		Outcome:  outcome,
+		Invoke:   at(invoke),
+		Complete: at(complete),
+	}
+}
+
+func v(n int) *int {
+	return history.IntPtr(n)
+}
+
+// indexed numbers ops in order, the way the recorder does.
+func indexed(ops ...history.Op) []history.Op {
+	for i := range ops {
+		ops[i].Index = i
+	}
+	return ops
+}
+
+// indices returns the indices of ops.
+func indices(ops []history.Op) []int {
+	var indices []int
+	for _, op := range ops {
+		indices = append(indices, op.Index)
+	}
+	return indices
+}
@@ -0,0 +1,346 @@
+package checker
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// AnomalyNonlinearizable is reported for a key whose operations cannot be
+// ordered into a sequential history of a register.
+const AnomalyNonlinearizable = "nonlinearizable"
+
+// absent is the register state of a key that was never written.
+const absent = -1
+
+// Linearizable checks that the operations on every key behave like a
+// linearizable register: every write, delete and read takes effect atomically
+// at some point between its invocation and completion. Failed operations are
+// ignored, and writes with an unknown outcome may take effect at any point
+// after their invocation or not at all. Keys that were only read are skipped.
+//
+// The search follows Wing & Gong as improved by Lowe, the algorithm behind
+// Knossos and Porcupine. For every key that cannot be linearized the result
+// holds a 1-minimal counterexample: a subset of its operations that is still
+// not linearizable, but becomes linearizable if any one operation is removed.
+// A positive timeout bounds the whole check; keys not decided in time make
+// the result unknown.
+func Linearizable(ops []history.Op, timeout time.Duration) Result {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	result := Result{Checker: "linearizability", Valid: Valid}
+	var unknown []int
+	keys := byKey(ops)
+	for _, key := range sortedKeys(keys) {
+		register := registerOps(keys[key])
+		if !written(register) {
+			// Keys of other instances are only read, their writes are in
+			// other histories.
+			continue
+		}
+		switch checkRegister(register, deadline) {
+		case Invalid:
+			counterexample := shrink(register, deadline)
+			result.Anomalies = append(result.Anomalies, Anomaly{
+				Kind:    AnomalyNonlinearizable,
+				Key:     key,
+				Message: fmt.Sprintf("%d operations on key %d have no linearization", len(counterexample), key),
+				Ops:     counterexample,
+			})
+		case Unknown:
+			unknown = append(unknown, key)
+		}
+	}
+
+	switch {
+	case len(result.Anomalies) > 0:
+		result.Valid = Invalid
+	case len(unknown) > 0:
+		result.Valid = Unknown
+		result.Detail = fmt.Sprintf("timed out after %s with %d of %d keys undecided", timeout, len(unknown), len(keys))
+	}
+	return result
+}
+
+func written(ops []history.Op) bool {
+	for _, op := range ops {
+		if op.F != history.Read {
+			return true
+		}
+	}
+	return false
+}
+
+// registerOps keeps the operations that may have taken effect.
+func registerOps(ops []history.Op) []history.Op {
+	var kept []history.Op
+	for _, op := range ops {
+		switch {
+		case op.Outcome == history.Fail:
+		case op.F == history.Read && op.Outcome != history.OK:
+		default:
+			kept = append(kept, op)
+		}
+	}
+	return kept
+}
+
+// shrink removes operations from a non-linearizable history for as long as
+// it stays non-linearizable, first in large chunks, then one by one. A write
+// is only removed together with the reads of its value, so the counterexample
+// shows the write a read observed instead of an unexplained value. shrink
+// returns what it has got so far when the deadline passes.
+func shrink(ops []history.Op, deadline time.Time) []history.Op {
+	current := ops
+	size := (len(current) + 1) / 2
+	for size >= 1 {
+		removed := false
+		for i := 0; i < len(current); {
+			if expired(deadline) {
+				return current
+			}
+			end := i + size
+			if end > len(current) {
+				end = len(current)
+			}
+			candidate := make([]history.Op, 0, len(current)-(end-i))
+			candidate = append(candidate, current[:i]...)
+			candidate = append(candidate, current[end:]...)
+			if readsFromWithin(candidate) && checkRegister(candidate, deadline) == Invalid {
+				current = candidate
+				removed = true
+			} else {
+				i = end
+			}
+		}
+		// Removing an operation can make an earlier one removable, so
+		// single operations are retried until none can be removed.
+		if size == 1 && !removed {
+			break
+		}
+		if size > 1 {
+			size /= 2
+		}
+	}
+	return current
+}
+
+// readsFromWithin reports whether every value read in ops is written in ops.
+func readsFromWithin(ops []history.Op) bool {
+	written := map[int]bool{}
+	for _, op := range ops {
+		if op.F == history.Write {
+			written[*op.Value] = true
+		}
+	}
+	for _, op := range ops {
+		if op.F == history.Read && op.Value != nil && !written[*op.Value] {
+			return false
+		}
+	}
+	return true
+}
+
+// entry is the invocation or completion of one operation in the doubly
+// linked list the search lifts linearized operations out of.
+type entry struct {
+	id    int
+	call  bool
+	time  int64
+	op    *history.Op
+	match *entry
+	prev  *entry
+	next  *entry
+}
+
+func (e *entry) lift() {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	m := e.match
+	m.prev.next = m.next
+	if m.next != nil {
+		m.next.prev = m.prev
+	}
+}
+
+func (e *entry) unlift() {
+	m := e.match
+	m.prev.next = m
+	if m.next != nil {
+		m.next.prev = m
+	}
+	e.prev.next = e
+	e.next.prev = e
+}
+
+// entries builds the list of invocations and completions ordered by time.
+// Writes with an unknown outcome never complete. On a tie invocations come
+// first, so touching operations count as concurrent.
+func entries(ops []history.Op) *entry {
+	list := make([]*entry, 0, 2*len(ops))
+	for i := range ops {
+		op := &ops[i]
+		complete := int64(math.MaxInt64)
+		if op.Outcome == history.OK {
+			complete = op.Complete.UnixNano()
+		}
+		call := &entry{id: i, call: true, time: op.Invoke.UnixNano(), op: op}
+		ret := &entry{id: i, time: complete, op: op, match: call}
+		call.match = ret
+		list = append(list, call, ret)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].time != list[j].time {
+			return list[i].time < list[j].time
+		}
+		return list[i].call && !list[j].call
+	})
+
+	head := &entry{}
+	prev := head
+	for _, e := range list {
+		e.prev = prev
+		prev.next = e
+		prev = e
+	}
+	return head
+}
+
+// step applies op to the register state, reporting whether op is allowed
+// in that state.
+func step(state int, op *history.Op) (int, bool) {
+	switch op.F {
+	case history.Write:
+		return *op.Value, true
+	case history.Delete:
+		return absent, true
+	}
+	if op.Value == nil {
+		return state, state == absent
+	}
+	return state, state == *op.Value
+}
+
+// checkRegister searches for a linearization of the operations of one key.
+func checkRegister(ops []history.Op, deadline time.Time) Validity {
+	head := entries(ops)
+	linearized := newBitset(len(ops))
+	cache := map[uint64][]cached{}
+	type frame struct {
+		e     *entry
+		state int
+	}
+	var stack []frame
+	state := absent
+
+	e := head.next
+	for steps := 0; head.next != nil; steps++ {
+		if steps%1024 == 0 && expired(deadline) {
+			return Unknown
+		}
+		if e.call {
+			if next, ok := step(state, e.op); ok {
+				candidate := linearized.clone()
+				candidate.set(e.id)
+				if remember(cache, candidate, next) {
+					stack = append(stack, frame{e: e, state: state})
+					state = next
+					linearized.set(e.id)
+					e.lift()
+					e = head.next
+					continue
+				}
+			}
+			e = e.next
+			continue
+		}
+
+		// An operation completed before any order of the remaining
+		// invocations could include it: undo the last choice.
+		if len(stack) == 0 {
+			return Invalid
+		}
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = top.state
+		linearized.clear(top.e.id)
+		top.e.unlift()
+		e = top.e.next
+	}
+	return Valid
+}
+
+// cached is a configuration the search has already explored.
+type cached struct {
+	linearized bitset
+	state      int
+}
+
+// remember adds the configuration to the cache, reporting false if it was
+// already there.
+func remember(cache map[uint64][]cached, linearized bitset, state int) bool {
+	h := linearized.hash() ^ uint64(state)*0x9e3779b97f4a7c15
+	for _, c := range cache[h] {
+		if c.state == state && c.linearized.equal(linearized) {
+			return false
+		}
+	}
+	cache[h] = append(cache[h], cached{linearized: linearized, state: state})
+	return true
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) clone() bitset {
+	return append(bitset(nil), b...)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << (i % 64)
+}
+
+func (b bitset) clear(i int) {
+	b[i/64] &^= 1 << (i % 64)
+}
+
+func (b bitset) equal(other bitset) bool {
+	for i := range b {
+		if b[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bitset) hash() uint64 {
+	// FNV-1a over the words.
+	h := uint64(14695981039346656037)
+	for _, w := range b {
+		h ^= w
+		h *= 1099511628211
+	}
+	return h
+}
+
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,219 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+func TestLinearizable(t *testing.T) {
+	w, d, r := history.Write, history.Delete, history.Read
+	ok, fail, info := history.OK, history.Fail, history.Info
+	for _, tc := range []struct {
+		name string
+		ops  []history.Op
+		want Validity
+		// counterexample are the indices of the counterexample, if
+		// the history is invalid.
+		counterexample []int
+	}{
+		{
+			name: "sequential",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, r, 1, v(1), ok, 20, 30),
+				op(0, w, 1, v(2), ok, 40, 50),
+				op(1, r, 1, v(2), ok, 60, 70),
+			),
+			want: Valid,
+		},
+		{
+			name: "read goes back while a write is in flight",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), ok, 20, 50),
+				op(1, r, 1, v(2), ok, 25, 30),
+				op(2, r, 1, v(1), ok, 35, 40),
+			),
+			// The second read sees 1 after the first saw 2, while the
+			// write of 2 was in flight: no order explains both.
+			want:           Invalid,
+			counterexample: []int{0, 1, 2, 3},
+		},
+		{
+			name: "concurrent reads see the old and the new value in order",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), ok, 20, 50),
+				op(1, r, 1, v(1), ok, 25, 30),
+				op(2, r, 1, v(2), ok, 30, 40),
+			),
+			want: Valid,
+		},
+		{
+			name: "stale read",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), ok, 20, 30),
+				op(1, r, 1, v(1), ok, 40, 50),
+			),
+			want:           Invalid,
+			counterexample: []int{0, 1, 2},
+		},
+		{
+			name: "lost write",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(1, r, 1, v(1), ok, 20, 30),
+				op(0, w, 2, v(1), ok, 20, 30),
+				op(1, r, 1, nil, ok, 40, 50),
+			),
+			want:           Invalid,
+			counterexample: []int{0, 3},
+		},
+		{
+			name: "read after delete",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, d, 1, v(2), ok, 20, 30),
+				op(1, r, 1, nil, ok, 40, 50),
+			),
+			want: Valid,
+		},
+		{
+			name: "indeterminate write applied",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), info, 20, 30),
+				op(1, r, 1, v(2), ok, 100, 110),
+			),
+			want: Valid,
+		},
+		{
+			name: "indeterminate write not applied",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), info, 20, 30),
+				op(1, r, 1, v(1), ok, 100, 110),
+			),
+			want: Valid,
+		},
+		{
+			name: "indeterminate write applied late",
+			// An unknown outcome may take effect any time after the
+			// write was invoked, even after later reads.
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), info, 20, 30),
+				op(1, r, 1, v(1), ok, 100, 110),
+				op(1, r, 1, v(2), ok, 120, 130),
+			),
+			want: Valid,
+		},
+		{
+			name: "indeterminate write read and then gone",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), info, 20, 30),
+				op(1, r, 1, v(2), ok, 100, 110),
+				op(1, r, 1, v(1), ok, 120, 130),
+			),
+			want:           Invalid,
+			counterexample: []int{0, 1, 2, 3},
+		},
+		{
+			name: "read of a failed write",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), fail, 20, 30),
+				op(1, r, 1, v(2), ok, 40, 50),
+			),
+			want: Invalid,
+		},
+		{
+			name: "keys only read are skipped",
+			ops: indexed(
+				op(0, r, 7, v(3), ok, 0, 10),
+				op(0, r, 7, v(1), ok, 20, 30),
+			),
+			want: Valid,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Linearizable(tc.ops, 0)
+			if result.Valid != tc.want {
+				t.Fatalf("got %s, want %s: %+v", result.Valid, tc.want, result.Anomalies)
+			}
+			if tc.want != Invalid {
+				return
+			}
+			if len(result.Anomalies) != 1 {
+				t.Fatalf("got %d anomalies, want 1", len(result.Anomalies))
+			}
+			a := result.Anomalies[0]
+			if a.Kind != AnomalyNonlinearizable || a.Key != 1 {
+				t.Errorf("got %s on key %d, want %s on key 1", a.Kind, a.Key, AnomalyNonlinearizable)
+			}
+			if tc.counterexample != nil && !reflect.DeepEqual(indices(a.Ops), tc.counterexample) {
+				t.Errorf("got counterexample %v, want %v", indices(a.Ops), tc.counterexample)
+			}
+			assertMinimal(t, a.Ops)
+		})
+	}
+}
+
+// assertMinimal checks that counterexample is not linearizable, but would be
+// without any one of its operations that shrink may remove.
+func assertMinimal(t *testing.T, counterexample []history.Op) {
+	t.Helper()
+	if checkRegister(counterexample, time.Time{}) != Invalid {
+		t.Fatalf("counterexample %v is linearizable", indices(counterexample))
+	}
+	for i := range counterexample {
+		rest := append(append([]history.Op{}, counterexample[:i]...), counterexample[i+1:]...)
+		if readsFromWithin(rest) && checkRegister(rest, time.Time{}) == Invalid {
+			t.Errorf("counterexample %v stays nonlinearizable without #%d", indices(counterexample), counterexample[i].Index)
+		}
+	}
+}
+
+func TestLinearizableMultipleKeys(t *testing.T) {
+	ops := indexed(
+		op(0, history.Write, 1, v(1), history.OK, 0, 10),
+		op(1, history.Write, 2, v(1), history.OK, 0, 10),
+		op(0, history.Read, 1, v(1), history.OK, 20, 30),
+		op(1, history.Read, 2, nil, history.OK, 20, 30),
+	)
+	result := Linearizable(ops, 0)
+	if result.Valid != Invalid || len(result.Anomalies) != 1 || result.Anomalies[0].Key != 2 {
+		t.Fatalf("got %s with %+v, want key 2 nonlinearizable", result.Valid, result.Anomalies)
+	}
+}
+
+func TestLinearizableShrink(t *testing.T) {
+	// Five writes each followed by a read of its value, then a read of the
+	// second value: the counterexample is that write, a later one and the
+	// stale read.
+	var ops []history.Op
+	for i := 1; i <= 5; i++ {
+		ops = append(ops,
+			op(0, history.Write, 1, v(i), history.OK, 40*i, 40*i+10),
+			op(1, history.Read, 1, v(i), history.OK, 40*i+20, 40*i+30),
+		)
+	}
+	ops = indexed(append(ops, op(1, history.Read, 1, v(2), history.OK, 300, 310))...)
+	result := Linearizable(ops, 0)
+	if result.Valid != Invalid || len(result.Anomalies) != 1 {
+		t.Fatalf("got %s with %d anomalies, want 1", result.Valid, len(result.Anomalies))
+	}
+	counterexample := result.Anomalies[0].Ops
+	if len(counterexample) != 3 {
+		t.Fatalf("got counterexample %v, want 3 operations", indices(counterexample))
+	}
+	if first, last := counterexample[0], counterexample[2]; first.F != history.Write || *first.Value != 2 || last.Index != 10 {
+		t.Errorf("got counterexample %v, want the write of 2 first and the stale read last", indices(counterexample))
+	}
+	assertMinimal(t, counterexample)
+}
@@ -0,0 +1,382 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// The anomalies of list-append histories, named after Adya.
+const (
+	// AnomalyG0 is a cycle of write-write dependencies: the transactions
+	// appended to several keys in orders no serial order explains.
+	AnomalyG0 = "G0"
+	// AnomalyG1a is a read of a value appended by a transaction that
+	// failed.
+	AnomalyG1a = "G1a"
+	// AnomalyG1c is a cycle of write-write and write-read dependencies,
+	// at least one of them write-read.
+	AnomalyG1c = "G1c"
+	// AnomalyGSingle is a cycle with exactly one read-write
+	// anti-dependency, e.g. a read skew.
+	AnomalyGSingle = "G-single"
+	// AnomalyG2 is a cycle with more than one read-write anti-dependency,
+	// e.g. a write skew.
+	AnomalyG2 = "G2"
+	// AnomalyIncompatibleOrder is two reads of a key that are not prefixes
+	// of each other, so the appends have no single order.
+	AnomalyIncompatibleOrder = "incompatible-order"
+)
+
+// dependency is the kind of a dependency between two transactions.
+type dependency uint8
+
+const (
+	// ww: the first transaction appended the element before the one the
+	// second appended.
+	ww dependency = 1 << iota
+	// wr: the second transaction read what the first appended last.
+	wr
+	// rw: the first transaction read a list the second appended to next.
+	rw
+
+	anyDependency = ww | wr | rw
+)
+
+func (d dependency) String() string {
+	switch d {
+	case ww:
+		return "ww"
+	case wr:
+		return "wr"
+	}
+	return "rw"
+}
+
+// dependencies is the dependency graph of a list-append history. Nodes are
+// indices into the history, and every edge holds the key each of its kinds
+// was inferred from.
+type dependencies struct {
+	out   map[int][]int
+	edges map[[2]int]map[dependency]int
+}
+
+func (g *dependencies) link(from, to int, kind dependency, key int) {
+	if from == to {
+		return
+	}
+	edge := [2]int{from, to}
+	kinds, ok := g.edges[edge]
+	if !ok {
+		kinds = map[dependency]int{}
+		g.edges[edge] = kinds
+		g.out[from] = append(g.out[from], to)
+	}
+	if _, ok := kinds[kind]; !ok {
+		kinds[kind] = key
+	}
+}
+
+// weakest returns the first kind of mask the edge from, to has, ww before wr
+// before rw.
+func (g *dependencies) weakest(from, to int, mask dependency) (dependency, bool) {
+	kinds := g.edges[[2]int{from, to}]
+	for _, kind := range []dependency{ww, wr, rw} {
+		if _, ok := kinds[kind]; ok && mask&kind != 0 {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+// components returns the strongly connected components of more than one
+// node of the graph restricted to the kinds of mask, with Tarjan's
+// algorithm.
+func (g *dependencies) components(mask dependency) [][]int {
+	index := map[int]int{}
+	low := map[int]int{}
+	onStack := map[int]bool{}
+	var stack []int
+	var components [][]int
+	var visit func(n int)
+	visit = func(n int) {
+		index[n] = len(index)
+		low[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, m := range g.out[n] {
+			if _, ok := g.weakest(n, m, mask); !ok {
+				continue
+			}
+			if _, seen := index[m]; !seen {
+				visit(m)
+				low[n] = min(low[n], low[m])
+			} else if onStack[m] {
+				low[n] = min(low[n], index[m])
+			}
+		}
+		if low[n] != index[n] {
+			return
+		}
+		var component []int
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			component = append(component, m)
+			if m == n {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Ints(component)
+			components = append(components, component)
+		}
+	}
+	for _, n := range sortedKeys(g.out) {
+		if _, seen := index[n]; !seen {
+			visit(n)
+		}
+	}
+	return components
+}
+
+// path returns the shortest path from one node to another over edges of the
+// kinds of mask between nodes of within, nil if there is none.
+func (g *dependencies) path(from, to int, mask dependency, within map[int]bool) []int {
+	parent := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == to {
+			path := []int{to}
+			for n != from {
+				n = parent[n]
+				path = append([]int{n}, path...)
+			}
+			return path
+		}
+		for _, m := range g.out[n] {
+			if _, seen := parent[m]; seen || !within[m] {
+				continue
+			}
+			if _, ok := g.weakest(n, m, mask); ok {
+				parent[m] = n
+				queue = append(queue, m)
+			}
+		}
+	}
+	return nil
+}
+
+// edgesOf returns the edges of kind between nodes of within, sorted.
+func (g *dependencies) edgesOf(kind dependency, within map[int]bool) [][2]int {
+	var edges [][2]int
+	for edge, kinds := range g.edges {
+		if _, ok := kinds[kind]; ok && within[edge[0]] && within[edge[1]] {
+			edges = append(edges, edge)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i][0] < edges[j][0] || (edges[i][0] == edges[j][0] && edges[i][1] < edges[j][1])
+	})
+	return edges
+}
+
+// ListAppend checks a history of transactions that append unique values to
+// lists and read whole lists, the way Elle does. The longest read of every
+// key gives the order of its appends, which every other read must be a
+// prefix of. From that order it infers the dependencies between the
+// transactions: write-write from consecutive appends, write-read from the
+// last element a read saw, and read-write anti-dependencies from the element
+// appended right after it. Every cycle of dependencies is an anomaly no
+// serializable database allows, and is reported as G0, G1c, G-single or G2,
+// the weakest anomaly of each strongly connected component of the graph,
+// with the transactions of the cycle as evidence.
+//
+// Failed transactions did not happen, so their appends must never be read.
+// Transactions with an unknown outcome may have happened: their appends take
+// part in the order once read, and the ones read are listed in
+// Result.Recovered, but what they read is unknown.
+func ListAppend(ops []history.Op) Result {
+	result := Result{Checker: "list-append", Valid: Valid}
+	appended := map[int]map[int]int{}
+	failed := map[int]map[int]int{}
+	order := map[int][]int{}
+	longest := map[int]int{}
+	for i, op := range ops {
+		if op.F != history.Txn {
+			continue
+		}
+		for _, m := range op.Mops {
+			switch {
+			case m.F == history.Append && op.Outcome == history.Fail:
+				if failed[m.Key] == nil {
+					failed[m.Key] = map[int]int{}
+				}
+				failed[m.Key][*m.Value] = i
+			case m.F == history.Append:
+				if appended[m.Key] == nil {
+					appended[m.Key] = map[int]int{}
+				}
+				appended[m.Key][*m.Value] = i
+			case m.F == history.Read && op.Outcome == history.OK && len(m.List) > len(order[m.Key]):
+				order[m.Key] = m.List
+				longest[m.Key] = i
+			}
+		}
+	}
+
+	g := &dependencies{out: map[int][]int{}, edges: map[[2]int]map[dependency]int{}}
+	for _, key := range sortedKeys(order) {
+		list := order[key]
+		for j := 1; j < len(list); j++ {
+			from, fromOK := appended[key][list[j-1]]
+			to, toOK := appended[key][list[j]]
+			if fromOK && toOK {
+				g.link(from, to, ww, key)
+			}
+		}
+	}
+	recovered := map[int]bool{}
+	reported := map[[2]int]bool{}
+	for i, op := range ops {
+		if op.F != history.Txn || op.Outcome != history.OK {
+			continue
+		}
+		for _, m := range op.Mops {
+			if m.F != history.Read {
+				continue
+			}
+			list := order[m.Key]
+			if !prefix(m.List, list) {
+				result.Anomalies = append(result.Anomalies, Anomaly{
+					Kind:    AnomalyIncompatibleOrder,
+					Key:     m.Key,
+					Message: fmt.Sprintf("key %d read as %v and as %v, neither extends the other", m.Key, m.List, list),
+					Ops:     []history.Op{op, ops[longest[m.Key]]},
+				})
+				continue
+			}
+			for _, v := range m.List {
+				if writer, ok := failed[m.Key][v]; ok && !reported[[2]int{m.Key, v}] {
+					reported[[2]int{m.Key, v}] = true
+					result.Anomalies = append(result.Anomalies, Anomaly{
+						Kind:    AnomalyG1a,
+						Key:     m.Key,
+						Message: fmt.Sprintf("key %d read %d whose transaction failed", m.Key, v),
+						Ops:     []history.Op{ops[writer], op},
+					})
+				}
+				if writer, ok := appended[m.Key][v]; ok && ops[writer].Outcome == history.Info && !recovered[writer] {
+					recovered[writer] = true
+					result.Recovered = append(result.Recovered, ops[writer])
+				}
+			}
+			if len(m.List) > 0 {
+				if writer, ok := appended[m.Key][m.List[len(m.List)-1]]; ok {
+					g.link(writer, i, wr, m.Key)
+				}
+			}
+			if len(m.List) < len(list) {
+				if writer, ok := appended[m.Key][list[len(m.List)]]; ok {
+					g.link(i, writer, rw, m.Key)
+				}
+			}
+		}
+	}
+
+	for _, component := range g.components(ww) {
+		within := set(component)
+		start := component[0]
+		for _, next := range g.out[start] {
+			if _, ok := g.weakest(start, next, ww); ok && within[next] {
+				result.Anomalies = append(result.Anomalies, g.cycle(ops, AnomalyG0, append([]int{start}, g.path(next, start, ww, within)...), ww))
+				break
+			}
+		}
+	}
+	for _, component := range g.components(ww | wr) {
+		within := set(component)
+		if edges := g.edgesOf(wr, within); len(edges) > 0 {
+			a, b := edges[0][0], edges[0][1]
+			result.Anomalies = append(result.Anomalies, g.cycle(ops, AnomalyG1c, append([]int{a}, g.path(b, a, ww|wr, within)...), ww|wr))
+		}
+	}
+	for _, component := range g.components(anyDependency) {
+		within := set(component)
+		edges := g.edgesOf(rw, within)
+		if len(edges) == 0 {
+			continue
+		}
+		found := false
+		for _, edge := range edges {
+			if path := g.path(edge[1], edge[0], ww|wr, within); path != nil {
+				result.Anomalies = append(result.Anomalies, g.cycle(ops, AnomalyGSingle, append([]int{edge[0]}, path...), ww|wr))
+				found = true
+				break
+			}
+		}
+		if !found {
+			a, b := edges[0][0], edges[0][1]
+			result.Anomalies = append(result.Anomalies, g.cycle(ops, AnomalyG2, append([]int{a}, g.path(b, a, anyDependency, within)...), anyDependency))
+		}
+	}
+
+	if len(result.Anomalies) > 0 {
+		result.Valid = Invalid
+	}
+	return result
+}
+
+// cycle describes the cycle of nodes, which ends where it starts. The first
+// edge of a G-single or G2 cycle is its read-write anti-dependency, the
+// others are described by their weakest kind of mask.
+func (g *dependencies) cycle(ops []history.Op, kind string, nodes []int, mask dependency) Anomaly {
+	var steps []string
+	var cycle []history.Op
+	key := 0
+	for j := 0; j+1 < len(nodes); j++ {
+		from, to := nodes[j], nodes[j+1]
+		dep, _ := g.weakest(from, to, mask)
+		if j == 0 && (kind == AnomalyGSingle || kind == AnomalyG2) {
+			dep = rw
+		}
+		k := g.edges[[2]int{from, to}][dep]
+		if j == 0 {
+			key = k
+		}
+		steps = append(steps, fmt.Sprintf("#%d %s on key %d -> #%d", ops[from].Index, dep, k, ops[to].Index))
+		cycle = append(cycle, ops[from])
+	}
+	return Anomaly{
+		Kind:    kind,
+		Key:     key,
+		Message: fmt.Sprintf("cycle of %d transactions: %s", len(cycle), strings.Join(steps, ", ")),
+		Ops:     cycle,
+	}
+}
+
+// prefix reports whether a is a prefix of b.
+func prefix(a, b []int) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func set(nodes []int) map[int]bool {
+	s := make(map[int]bool, len(nodes))
+	for _, n := range nodes {
+		s[n] = true
+	}
+	return s
+}
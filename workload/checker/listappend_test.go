@@ -0,0 +1,206 @@
+package checker
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// txn returns a transaction of process running mops, invoked and completed
+// at the given milliseconds.
+func txn(process int, outcome history.Outcome, invoke, complete int, mops ...history.Mop) history.Op {
+	t := op(process, history.Txn, mops[0].Key, nil, outcome, invoke, complete)
+	t.Mops = mops
+	return t
+}
+
+func appendMop(key, value int) history.Mop {
+	return history.Mop{F: history.Append, Key: key, Value: v(value)}
+}
+
+func readMop(key int, list ...int) history.Mop {
+	return history.Mop{F: history.Read, Key: key, List: list}
+}
+
+func TestListAppend(t *testing.T) {
+	const x, y = 1, 2
+	ok, fail, info := history.OK, history.Fail, history.Info
+	for _, tc := range []struct {
+		name string
+		ops  []history.Op
+		want Validity
+		// anomalies are the kinds found, and cycle the indices of the
+		// transactions of the first one.
+		anomalies []string
+		cycle     []int
+		recovered []int
+	}{
+		{
+			name: "serial",
+			ops: indexed(
+				txn(0, ok, 0, 10, appendMop(x, 1)),
+				txn(1, ok, 20, 30, readMop(x, 1), appendMop(x, 2), appendMop(y, 3)),
+				txn(0, ok, 40, 50, readMop(x, 1, 2), readMop(y, 3)),
+				txn(2, ok, 60, 70, readMop(y, 3), appendMop(y, 4), readMop(y, 3, 4)),
+			),
+			want: Valid,
+		},
+		{
+			name: "write cycle",
+			// T0 appended to x before T1 but to y after it.
+			ops: indexed(
+				txn(0, ok, 0, 10, appendMop(x, 1), appendMop(y, 1)),
+				txn(1, ok, 0, 10, appendMop(x, 2), appendMop(y, 2)),
+				txn(2, ok, 20, 30, readMop(x, 1, 2), readMop(y, 2, 1)),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyG0},
+			cycle:     []int{0, 1},
+		},
+		{
+			name: "circular information flow",
+			// Each transaction read what the other appended.
+			ops: indexed(
+				txn(0, ok, 0, 10, appendMop(x, 1), readMop(y, 1)),
+				txn(1, ok, 0, 10, appendMop(y, 1), readMop(x, 1)),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyG1c},
+			cycle:     []int{0, 1},
+		},
+		{
+			name: "read skew",
+			// T0 read x before T1 appended to it, and y after.
+			ops: indexed(
+				txn(0, ok, 0, 10, readMop(x), readMop(y, 1)),
+				txn(1, ok, 0, 10, appendMop(x, 1), appendMop(y, 1)),
+				txn(2, ok, 20, 30, readMop(x, 1), readMop(y, 1)),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyGSingle},
+			cycle:     []int{0, 1},
+		},
+		{
+			name: "write skew",
+			// Each transaction read the list the other appended to
+			// before it did.
+			ops: indexed(
+				txn(0, ok, 0, 10, readMop(x), appendMop(y, 1)),
+				txn(1, ok, 0, 10, readMop(y), appendMop(x, 1)),
+				txn(2, ok, 20, 30, readMop(x, 1), readMop(y, 1)),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyG2},
+			cycle:     []int{0, 1},
+		},
+		{
+			name: "aborted read",
+			ops: indexed(
+				txn(0, fail, 0, 10, appendMop(x, 1)),
+				txn(1, ok, 20, 30, readMop(x, 1)),
+				txn(2, ok, 40, 50, readMop(x, 1)),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyG1a},
+		},
+		{
+			name: "incompatible order",
+			ops: indexed(
+				txn(0, ok, 0, 10, appendMop(x, 1)),
+				txn(1, ok, 0, 10, appendMop(x, 2)),
+				txn(2, ok, 20, 30, readMop(x, 1, 2)),
+				txn(3, ok, 20, 30, readMop(x, 2)),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyIncompatibleOrder},
+		},
+		{
+			name: "indeterminate transaction read",
+			ops: indexed(
+				txn(0, info, 0, 10, appendMop(x, 1), appendMop(y, 1)),
+				txn(1, ok, 20, 30, readMop(x, 1)),
+				txn(1, ok, 40, 50, readMop(y, 1)),
+			),
+			want:      Valid,
+			recovered: []int{0},
+		},
+		{
+			name: "indeterminate transaction not read",
+			ops: indexed(
+				txn(0, info, 0, 10, appendMop(x, 1)),
+				txn(1, ok, 20, 30, readMop(x)),
+			),
+			want: Valid,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ListAppend(tc.ops)
+			if result.Valid != tc.want {
+				t.Errorf("got %s, want %s", result.Valid, tc.want)
+			}
+			var kinds []string
+			for _, a := range result.Anomalies {
+				kinds = append(kinds, a.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tc.anomalies) {
+				t.Fatalf("got anomalies %v, want %v", kinds, tc.anomalies)
+			}
+			if tc.cycle != nil {
+				if got := indices(result.Anomalies[0].Ops); !reflect.DeepEqual(got, tc.cycle) {
+					t.Errorf("got cycle %v, want %v: %s", got, tc.cycle, result.Anomalies[0].Message)
+				}
+			}
+			if got := indices(result.Recovered); !reflect.DeepEqual(got, tc.recovered) {
+				t.Errorf("got recovered %v, want %v", got, tc.recovered)
+			}
+		})
+	}
+}
+
+func TestListAppendIgnoresRegisters(t *testing.T) {
+	ops := indexed(
+		op(0, history.Write, 1, v(1), history.OK, 0, 10),
+		op(0, history.Read, 1, v(0), history.OK, 20, 30),
+	)
+	if result := ListAppend(ops); result.Valid != Valid || len(result.Anomalies) != 0 {
+		t.Errorf("got %s with %v, want valid", result.Valid, result.Anomalies)
+	}
+}
+
+func TestDependencyComponents(t *testing.T) {
+	g := &dependencies{out: map[int][]int{}, edges: map[[2]int]map[dependency]int{}}
+	// 0 and 1 depend on each other by ww and wr, 2 and 3 only through an
+	// anti-dependency, and 4 hangs off the first cycle.
+	g.link(0, 1, ww, 1)
+	g.link(1, 0, wr, 1)
+	g.link(2, 3, wr, 2)
+	g.link(3, 2, rw, 2)
+	g.link(1, 4, ww, 3)
+	g.link(4, 4, ww, 3)
+	for _, tc := range []struct {
+		mask dependency
+		want [][]int
+	}{
+		{mask: ww, want: nil},
+		{mask: ww | wr, want: [][]int{{0, 1}}},
+		{mask: anyDependency, want: [][]int{{0, 1}, {2, 3}}},
+	} {
+		got := g.components(tc.mask)
+		sortComponents(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("components of %03b: got %v, want %v", tc.mask, got, tc.want)
+		}
+	}
+	if got := g.edgesOf(rw, set([]int{2, 3})); !reflect.DeepEqual(got, [][2]int{{3, 2}}) {
+		t.Errorf("got rw edges %v, want [[3 2]]", got)
+	}
+	if got := g.path(1, 0, ww|wr, set([]int{0, 1})); !reflect.DeepEqual(got, []int{1, 0}) {
+		t.Errorf("got path %v, want [1 0]", got)
+	}
+}
+
+func sortComponents(components [][]int) {
+	sort.Slice(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+}
@@ -0,0 +1,101 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+const (
+	// AnomalyLostWrite is reported for an acknowledged write that a later
+	// read no longer sees.
+	AnomalyLostWrite = "lost-write"
+	// AnomalyUnexpected is reported for a read of a value whose write
+	// definitely failed.
+	AnomalyUnexpected = "unexpected"
+)
+
+// LostWrites tells acknowledged, failed and indeterminate writes apart. Values
+// grow per key, so an acknowledged write is provably lost when the last read
+// of its key started after the write completed returns a smaller value or no
+// value. No value is fine after a delete, which takes a value in the order of
+// writes too, that did not fail. A read of a value whose write failed is
+// unexpected. Writes with an unknown outcome are never reported as lost; the
+// ones a read observed are listed in Result.Recovered.
+func LostWrites(ops []history.Op) Result {
+	result := Result{Checker: "lost-writes", Valid: Valid}
+	keys := byKey(ops)
+	for _, key := range sortedKeys(keys) {
+		var acked, final, deleted *history.Op
+		written := map[int]*history.Op{}
+		for i, op := range keys[key] {
+			if op.F == history.Read {
+				continue
+			}
+			if op.F == history.Write {
+				written[*op.Value] = &keys[key][i]
+			}
+			if op.F == history.Delete && op.Outcome != history.Fail && (deleted == nil || *op.Value > *deleted.Value) {
+				deleted = &keys[key][i]
+			}
+			if op.Outcome == history.OK && (acked == nil || *op.Value > *acked.Value) {
+				acked = &keys[key][i]
+			}
+		}
+
+		// Every failed or indeterminate write is reported once, by the
+		// first read that observed it.
+		observed := map[int]bool{}
+		for i, op := range keys[key] {
+			if op.F != history.Read || op.Outcome != history.OK {
+				continue
+			}
+			if acked != nil && op.Invoke.After(acked.Complete) {
+				final = &keys[key][i]
+			}
+			if op.Value == nil {
+				continue
+			}
+			write, ok := written[*op.Value]
+			if !ok || observed[*op.Value] {
+				continue
+			}
+			observed[*op.Value] = true
+			switch write.Outcome {
+			case history.Fail:
+				result.Anomalies = append(result.Anomalies, Anomaly{
+					Kind:    AnomalyUnexpected,
+					Key:     key,
+					Message: fmt.Sprintf("key %d read %d whose write failed", key, *op.Value),
+					Ops:     []history.Op{*write, op},
+				})
+			case history.Info:
+				result.Recovered = append(result.Recovered, *write)
+			}
+		}
+
+		if final == nil || (final.Value != nil && *final.Value >= *acked.Value) {
+			continue
+		}
+		if final.Value == nil && deleted != nil && *deleted.Value >= *acked.Value {
+			continue
+		}
+		result.Anomalies = append(result.Anomalies, Anomaly{
+			Kind:    AnomalyLostWrite,
+			Key:     key,
+			Message: fmt.Sprintf("acknowledged %s lost: key %d last read %s after %d was acknowledged", acked.F, key, valueString(final.Value), *acked.Value),
+			Ops:     []history.Op{*acked, *final},
+		})
+	}
+	if len(result.Anomalies) > 0 {
+		result.Valid = Invalid
+	}
+	return result
+}
+
+func valueString(v *int) string {
+	if v == nil {
+		return "no value"
+	}
+	return fmt.Sprint(*v)
+}
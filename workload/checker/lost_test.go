@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+func TestLostWrites(t *testing.T) {
+	w, d, r := history.Write, history.Delete, history.Read
+	ok, fail, info := history.OK, history.Fail, history.Info
+	for _, tc := range []struct {
+		name      string
+		ops       []history.Op
+		want      Validity
+		anomalies []string
+		// recovered are the indices of the recovered writes.
+		recovered []int
+	}{
+		{
+			name: "intact",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), ok, 20, 30),
+				op(1, r, 1, v(2), ok, 40, 50),
+			),
+			want: Valid,
+		},
+		{
+			name: "acknowledged write lost",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), ok, 20, 30),
+				op(1, r, 1, v(1), ok, 40, 50),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyLostWrite},
+		},
+		{
+			name: "key gone",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(1, r, 1, nil, ok, 20, 30),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyLostWrite},
+		},
+		{
+			name: "read concurrent with the acknowledged write",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), ok, 20, 30),
+				op(1, r, 1, v(1), ok, 25, 35),
+			),
+			want: Valid,
+		},
+		{
+			name: "key deleted",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, d, 1, v(2), info, 20, 30),
+				op(1, r, 1, nil, ok, 40, 50),
+			),
+			want: Valid,
+		},
+		{
+			name: "indeterminate write recovered",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), info, 20, 30),
+				op(1, r, 1, v(2), ok, 40, 50),
+				op(1, r, 1, v(2), ok, 60, 70),
+			),
+			want:      Valid,
+			recovered: []int{1},
+		},
+		{
+			name: "indeterminate write not applied",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), info, 20, 30),
+				op(1, r, 1, v(1), ok, 40, 50),
+			),
+			want: Valid,
+		},
+		{
+			name: "failed write read",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(0, w, 1, v(2), fail, 20, 30),
+				op(1, r, 1, v(2), ok, 40, 50),
+				op(1, r, 1, v(2), ok, 60, 70),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyUnexpected},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := LostWrites(tc.ops)
+			if result.Valid != tc.want {
+				t.Errorf("got %s, want %s", result.Valid, tc.want)
+			}
+			var kinds []string
+			for _, a := range result.Anomalies {
+				kinds = append(kinds, a.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tc.anomalies) {
+				t.Errorf("got anomalies %v, want %v", kinds, tc.anomalies)
+			}
+			if got := indices(result.Recovered); !reflect.DeepEqual(got, tc.recovered) {
+				t.Errorf("got recovered %v, want %v", got, tc.recovered)
+			}
+		})
+	}
+}
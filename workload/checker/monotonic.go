@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// AnomalyNonmonotonicRead is reported when a process reads an older value of
+// a key than it read before.
+const AnomalyNonmonotonicRead = "nonmonotonic-read"
+
+// MonotonicReads checks that the successive reads of every process never go
+// back in time: values grow per key, so a read may not return a smaller
+// value, or no value, after a process has seen a value. No value is fine for
+// keys that were deleted, but later values still may not go back.
+func MonotonicReads(ops []history.Op) Result {
+	result := Result{Checker: "monotonic-reads", Valid: Valid}
+	deleted := map[int]bool{}
+	for _, op := range ops {
+		if op.F == history.Delete && op.Outcome != history.Fail {
+			deleted[op.Key] = true
+		}
+	}
+	type session struct {
+		process int
+		key     int
+	}
+	last := map[session]history.Op{}
+	for _, op := range ops {
+		if op.F != history.Read || op.Outcome != history.OK {
+			continue
+		}
+		if op.Value == nil && deleted[op.Key] {
+			continue
+		}
+		s := session{process: op.Process, key: op.Key}
+		prev, ok := last[s]
+		last[s] = op
+		if !ok || prev.Value == nil || (op.Value != nil && *op.Value >= *prev.Value) {
+			continue
+		}
+		result.Anomalies = append(result.Anomalies, Anomaly{
+			Kind: AnomalyNonmonotonicRead,
+			Key:  op.Key,
+			Message: fmt.Sprintf("process %d read %s from key %d after reading %d",
+				op.Process, valueString(op.Value), op.Key, *prev.Value),
+			Ops: []history.Op{prev, op},
+		})
+	}
+	if len(result.Anomalies) > 0 {
+		result.Valid = Invalid
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+func TestMonotonicReads(t *testing.T) {
+	w, d, r := history.Write, history.Delete, history.Read
+	ok, fail := history.OK, history.Fail
+	for _, tc := range []struct {
+		name string
+		ops  []history.Op
+		want Validity
+		// anomaly are the indices of the reads of the anomaly, if any.
+		anomaly []int
+	}{
+		{
+			name: "growing reads",
+			ops: indexed(
+				op(1, r, 1, nil, ok, 0, 10),
+				op(1, r, 1, v(1), ok, 20, 30),
+				op(1, r, 1, v(1), ok, 40, 50),
+				op(1, r, 1, v(3), ok, 60, 70),
+			),
+			want: Valid,
+		},
+		{
+			name: "read goes back",
+			ops: indexed(
+				op(1, r, 1, v(2), ok, 0, 10),
+				op(1, r, 1, v(1), ok, 20, 30),
+			),
+			want:    Invalid,
+			anomaly: []int{0, 1},
+		},
+		{
+			name: "value disappears",
+			ops: indexed(
+				op(1, r, 1, v(2), ok, 0, 10),
+				op(1, r, 1, nil, ok, 20, 30),
+			),
+			want:    Invalid,
+			anomaly: []int{0, 1},
+		},
+		{
+			name: "other processes may go back",
+			ops: indexed(
+				op(1, r, 1, v(2), ok, 0, 10),
+				op(2, r, 1, v(1), ok, 20, 30),
+			),
+			want: Valid,
+		},
+		{
+			name: "other keys are separate",
+			ops: indexed(
+				op(1, r, 1, v(2), ok, 0, 10),
+				op(1, r, 2, v(1), ok, 20, 30),
+			),
+			want: Valid,
+		},
+		{
+			name: "failed reads are ignored",
+			ops: indexed(
+				op(1, r, 1, v(2), ok, 0, 10),
+				op(1, r, 1, nil, fail, 20, 30),
+			),
+			want: Valid,
+		},
+		{
+			name: "no value after a delete",
+			ops: indexed(
+				op(0, w, 1, v(1), ok, 0, 10),
+				op(1, r, 1, v(1), ok, 20, 30),
+				op(0, d, 1, v(2), ok, 40, 50),
+				op(1, r, 1, nil, ok, 60, 70),
+			),
+			want: Valid,
+		},
+		{
+			name: "older value after a delete",
+			ops: indexed(
+				op(1, r, 1, v(3), ok, 0, 10),
+				op(0, d, 1, v(4), ok, 20, 30),
+				op(1, r, 1, nil, ok, 40, 50),
+				op(1, r, 1, v(2), ok, 60, 70),
+			),
+			want:    Invalid,
+			anomaly: []int{0, 3},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := MonotonicReads(tc.ops)
+			if result.Valid != tc.want {
+				t.Fatalf("got %s, want %s", result.Valid, tc.want)
+			}
+			if tc.anomaly == nil {
+				return
+			}
+			if len(result.Anomalies) != 1 {
+				t.Fatalf("got %d anomalies, want 1", len(result.Anomalies))
+			}
+			a := result.Anomalies[0]
+			if a.Kind != AnomalyNonmonotonicRead || !reflect.DeepEqual(indices(a.Ops), tc.anomaly) {
+				t.Errorf("got %s of %v, want %s of %v", a.Kind, indices(a.Ops), AnomalyNonmonotonicRead, tc.anomaly)
+			}
+		})
+	}
+}
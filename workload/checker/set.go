@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+const (
+	// AnomalyLostElement is reported for an acknowledged add whose element
+	// the final read of the set misses.
+	AnomalyLostElement = "lost-element"
+	// AnomalyUnexpectedElement is reported for an element of the final read
+	// that was never added, or whose add failed.
+	AnomalyUnexpectedElement = "unexpected-element"
+)
+
+// Set checks a grow-only set against its final read, the last ok read-set of
+// the history: every acknowledged add must be in it, and every element in it
+// must have been added by an add that did not fail. Adds with an unknown
+// outcome may be in it or not; the ones that are are listed in
+// Result.Recovered. Without a final read the result is unknown.
+func Set(ops []history.Op) Result {
+	result := Result{Checker: "set", Valid: Valid}
+	adds := map[int]history.Op{}
+	var final *history.Op
+	for i, op := range ops {
+		switch {
+		case op.F == history.Add:
+			adds[*op.Value] = op
+		case op.F == history.ReadSet && op.Outcome == history.OK:
+			final = &ops[i]
+		}
+	}
+	if len(adds) == 0 {
+		return result
+	}
+	if final == nil {
+		result.Valid = Unknown
+		result.Detail = fmt.Sprintf("no final read of the set to check %d adds against", len(adds))
+		return result
+	}
+
+	read := map[int]bool{}
+	for _, element := range final.Set {
+		read[element] = true
+	}
+	for _, element := range sortedKeys(adds) {
+		add := adds[element]
+		switch {
+		case add.Outcome == history.OK && !read[element]:
+			result.Anomalies = append(result.Anomalies, Anomaly{
+				Kind:    AnomalyLostElement,
+				Key:     element,
+				Message: fmt.Sprintf("acknowledged element %d missing from the final read of %d elements", element, len(final.Set)),
+				Ops:     []history.Op{add, *final},
+			})
+		case add.Outcome == history.Fail && read[element]:
+			result.Anomalies = append(result.Anomalies, Anomaly{
+				Kind:    AnomalyUnexpectedElement,
+				Key:     element,
+				Message: fmt.Sprintf("element %d read whose add failed", element),
+				Ops:     []history.Op{add, *final},
+			})
+		case add.Outcome == history.Info && read[element]:
+			result.Recovered = append(result.Recovered, add)
+		}
+	}
+	for _, element := range sortedKeys(read) {
+		if _, ok := adds[element]; !ok {
+			result.Anomalies = append(result.Anomalies, Anomaly{
+				Kind:    AnomalyUnexpectedElement,
+				Key:     element,
+				Message: fmt.Sprintf("element %d read that was never added", element),
+				Ops:     []history.Op{*final},
+			})
+		}
+	}
+	if len(result.Anomalies) > 0 {
+		result.Valid = Invalid
+	}
+	return result
+}
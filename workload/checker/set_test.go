@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// readSet returns a final read of the set returning elements.
+func readSet(outcome history.Outcome, invoke, complete int, elements ...int) history.Op {
+	read := op(0, history.ReadSet, 0, nil, outcome, invoke, complete)
+	read.Set = elements
+	return read
+}
+
+func TestSet(t *testing.T) {
+	add := history.Add
+	ok, fail, info := history.OK, history.Fail, history.Info
+	for _, tc := range []struct {
+		name string
+		ops  []history.Op
+		want Validity
+		// anomalies are the kinds found, with the element of each.
+		anomalies []string
+		elements  []int
+		recovered []int
+	}{
+		{
+			name: "every acknowledged element read",
+			ops: indexed(
+				op(1, add, 1, v(1), ok, 0, 10),
+				op(1, add, 2, v(2), ok, 20, 30),
+				op(1, add, 3, v(3), fail, 40, 50),
+				readSet(ok, 100, 110, 1, 2),
+			),
+			want: Valid,
+		},
+		{
+			name: "acknowledged element lost",
+			ops: indexed(
+				op(1, add, 1, v(1), ok, 0, 10),
+				op(1, add, 2, v(2), ok, 20, 30),
+				readSet(ok, 100, 110, 1),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyLostElement},
+			elements:  []int{2},
+		},
+		{
+			name: "indeterminate add recovered",
+			ops: indexed(
+				op(1, add, 1, v(1), ok, 0, 10),
+				op(1, add, 2, v(2), info, 20, 30),
+				op(2, add, 3, v(3), info, 20, 30),
+				readSet(ok, 100, 110, 1, 2),
+			),
+			want:      Valid,
+			recovered: []int{1},
+		},
+		{
+			name: "unexpected elements",
+			ops: indexed(
+				op(1, add, 1, v(1), ok, 0, 10),
+				op(1, add, 2, v(2), fail, 20, 30),
+				readSet(ok, 100, 110, 1, 2, 7),
+			),
+			want:      Invalid,
+			anomalies: []string{AnomalyUnexpectedElement, AnomalyUnexpectedElement},
+			elements:  []int{2, 7},
+		},
+		{
+			name: "final read failed",
+			ops: indexed(
+				op(1, add, 1, v(1), ok, 0, 10),
+				readSet(fail, 100, 110),
+			),
+			want: Unknown,
+		},
+		{
+			name: "last ok read is final",
+			ops: indexed(
+				op(1, add, 1, v(1), ok, 0, 10),
+				readSet(ok, 20, 30),
+				readSet(ok, 100, 110, 1),
+				readSet(fail, 120, 130),
+			),
+			want: Valid,
+		},
+		{
+			name: "no adds",
+			ops:  indexed(readSet(ok, 100, 110)),
+			want: Valid,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Set(tc.ops)
+			if result.Valid != tc.want {
+				t.Errorf("got %s, want %s: %s", result.Valid, tc.want, result.Detail)
+			}
+			var kinds []string
+			var elements []int
+			for _, a := range result.Anomalies {
+				kinds = append(kinds, a.Kind)
+				elements = append(elements, a.Key)
+			}
+			if !reflect.DeepEqual(kinds, tc.anomalies) || !reflect.DeepEqual(elements, tc.elements) {
+				t.Errorf("got anomalies %v of %v, want %v of %v", kinds, elements, tc.anomalies, tc.elements)
+			}
+			if got := indices(result.Recovered); !reflect.DeepEqual(got, tc.recovered) {
+				t.Errorf("got recovered %v, want %v", got, tc.recovered)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package workload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the state a restarted workload resumes from: how far the
+// sequence got and what is known about every key.
+type Checkpoint struct {
+	Time     time.Time `json:"time"`
+	KeySpace int       `json:"key_space"`
+	Instance int       `json:"instance"`
+	// KeyDistribution, Seed, Mix and Preload plan the keys and operations
+	// of the sequences from Sequence up to Lease.
+	KeyDistribution string `json:"key_distribution"`
+	Seed            int64  `json:"seed"`
+	Mix             string `json:"mix,omitempty"`
+	Preload         bool   `json:"preload,omitempty"`
+	// Sequence is the next sequence to issue when the checkpoint was taken.
+	// No sequence at or above Lease was issued before the next checkpoint,
+	// so a restarted workload continues at Lease.
+	Sequence int `json:"sequence"`
+	Lease    int `json:"lease"`
+	// Pending are the writes issued before Sequence that had not completed.
+	Pending []PendingWrite  `json:"pending,omitempty"`
+	Keys    []KeyCheckpoint `json:"keys"`
+}
+
+// PendingWrite is a write that was in flight when a checkpoint was taken.
+type PendingWrite struct {
+	Sequence int       `json:"sequence"`
+	Key      int       `json:"key"`
+	Value    int       `json:"value"`
+	Delete   bool      `json:"delete,omitempty"`
+	Invoke   time.Time `json:"invoke"`
+}
+
+// KeyCheckpoint is what was known about one key.
+type KeyCheckpoint struct {
+	Key int `json:"key"`
+	// Next is the value of the next write issued to the key.
+	Next          int   `json:"next"`
+	Acked         int   `json:"acked"`
+	Failed        []int `json:"failed,omitempty"`
+	Indeterminate []int `json:"indeterminate,omitempty"`
+	Recovered     []int `json:"recovered,omitempty"`
+	Deletes       []int `json:"deletes,omitempty"`
+}
+
+// LoadCheckpoint reads the checkpoint at path. It returns nil if there is
+// none, so the workload starts afresh.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// checkpointer saves checkpoints to a file, e.g. on a PVC. The file is
+// replaced atomically, so a crash leaves either the old or the new one.
+type checkpointer struct {
+	mu       sync.Mutex
+	path     string
+	keySpace int
+	instance int
+	dist     string
+	seed     int64
+	mix      string
+	preload  bool
+	// lease is the first sequence that may not be issued before a
+	// checkpoint covering it was saved.
+	lease int
+}
+
+func (c *checkpointer) save(k *keys) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp := k.checkpoint(c.lease)
+	cp.KeySpace = c.keySpace
+	cp.Instance = c.instance
+	cp.KeyDistribution = c.dist
+	cp.Seed = c.seed
+	cp.Mix = c.mix
+	cp.Preload = c.preload
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// extend moves the lease past sequence and saves a checkpoint covering it.
+// It must be called before sequence is issued.
+func (c *checkpointer) extend(sequence, by int, k *keys) error {
+	c.mu.Lock()
+	if sequence < c.lease {
+		c.mu.Unlock()
+		return nil
+	}
+	c.lease = sequence + by
+	c.mu.Unlock()
+	return c.save(k)
+}
+
+func (k *keys) checkpoint(lease int) Checkpoint {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	cp := Checkpoint{
+		Time:     time.Now(),
+		Sequence: k.next,
+		Lease:    lease,
+	}
+	for _, pending := range k.pending {
+		cp.Pending = append(cp.Pending, pending)
+	}
+	sort.Slice(cp.Pending, func(i, j int) bool { return cp.Pending[i].Sequence < cp.Pending[j].Sequence })
+	for key, s := range k.state {
+		cp.Keys = append(cp.Keys, KeyCheckpoint{
+			Key:           key,
+			Next:          s.next,
+			Acked:         s.acked,
+			Failed:        sortedValues(s.failed),
+			Indeterminate: sortedValues(s.indeterminate),
+			Recovered:     sortedValues(s.recovered),
+			Deletes:       sortedValues(s.deletes),
+		})
+	}
+	sort.Slice(cp.Keys, func(i, j int) bool { return cp.Keys[i].Key < cp.Keys[j].Key })
+	return cp
+}
+
+// restore loads the key states of cp. The writes that were pending, and the
+// ones that may have been issued after cp was taken, have an unknown outcome;
+// restore returns them as pending writes, with the time of cp as the
+// invocation time of the latter, whose keys and operations are picked by
+// plan.
+func (k *keys) restore(cp *Checkpoint, plan func(sequence int) (int, OpType)) []PendingWrite {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, kc := range cp.Keys {
+		s := k.get(kc.Key)
+		s.next = kc.Next
+		s.acked = kc.Acked
+		for _, v := range kc.Failed {
+			s.failed[v] = true
+		}
+		for _, v := range kc.Indeterminate {
+			s.indeterminate[v] = true
+		}
+		for _, v := range kc.Recovered {
+			s.recovered[v] = true
+		}
+		for _, v := range kc.Deletes {
+			s.deletes[v] = true
+		}
+	}
+	k.next = cp.Lease
+
+	unknown := append([]PendingWrite(nil), cp.Pending...)
+	for sequence := cp.Sequence; sequence < cp.Lease; sequence++ {
+		key, op := plan(sequence)
+		if !op.mutates() {
+			continue
+		}
+		s := k.get(key)
+		pending := PendingWrite{Sequence: sequence, Key: key, Value: s.next, Delete: op == OpDelete, Invoke: cp.Time}
+		if pending.Delete {
+			s.deletes[pending.Value] = true
+		}
+		unknown = append(unknown, pending)
+		s.next++
+	}
+	return unknown
+}
+
+func sortedValues(m map[int]bool) []int {
+	values := make([]int, 0, len(m))
+	for v := range m {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values
+}
@@ -0,0 +1,323 @@
+// Package coordinator merges the results of several workload instances
+// running against the same cluster into one cluster-wide timeline.
+//
+// Instances push their interval stats, latencies, anomalies and summary to
+// a Coordinator, over HTTP with a Pusher or in-process with
+// Coordinator.Reporter, and the Coordinator serves the merged Timeline.
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+// Batch is what one instance pushes at a time.
+type Batch struct {
+	Client    string                   `json:"client"`
+	Intervals []workload.IntervalEvent `json:"intervals,omitempty"`
+	Latencies []workload.LatencyEvent  `json:"latencies,omitempty"`
+	Anomalies []workload.AnomalyEvent  `json:"anomalies,omitempty"`
+	Summary   *workload.Summary        `json:"summary,omitempty"`
+}
+
+// Point is one bucket of the cluster-wide timeline.
+type Point struct {
+	Type string    `json:"type,omitempty"`
+	Time time.Time `json:"ts"`
+	// Clients is the number of instances that reported the bucket.
+	Clients int `json:"clients"`
+	Ops     int `json:"ops"`
+	OK      int `json:"ok"`
+	Failed  int `json:"failed"`
+	// Throughput is the number of successful writes per second.
+	Throughput   float64   `json:"throughput"`
+	Availability float64   `json:"availability"`
+	Latencies    []Latency `json:"latencies,omitempty"`
+}
+
+// Latency merges the latencies of one operation type within a bucket.
+// Percentiles cannot be merged exactly, so each is the highest any instance
+// reported, an upper bound of the cluster-wide percentile.
+type Latency struct {
+	Op    workload.OpType `json:"op"`
+	Count int64           `json:"count"`
+	P50Ms float64         `json:"p50_ms"`
+	P99Ms float64         `json:"p99_ms"`
+	MaxMs float64         `json:"max_ms"`
+}
+
+// Client is what the coordinator knows about one instance.
+type Client struct {
+	Client   string    `json:"client"`
+	LastPush time.Time `json:"last_push"`
+	// Summary is nil until the instance has finished its run.
+	Summary *workload.Summary `json:"summary,omitempty"`
+}
+
+// Timeline is the merged view of all instances.
+type Timeline struct {
+	Bucket    string                  `json:"bucket"`
+	Points    []Point                 `json:"points"`
+	Anomalies []workload.AnomalyEvent `json:"anomalies"`
+	Clients   []Client                `json:"clients"`
+}
+
+// ClusterSummary sums up the runs of all instances.
+type ClusterSummary struct {
+	Type         string         `json:"type,omitempty"`
+	Clients      int            `json:"clients"`
+	Finished     int            `json:"finished"`
+	Ops          int            `json:"ops"`
+	OK           int            `json:"ok"`
+	Availability float64        `json:"availability"`
+	Anomalies    map[string]int `json:"anomalies"`
+	Passed       bool           `json:"passed"`
+	Violations   []string       `json:"violations,omitempty"`
+}
+
+type point struct {
+	Point
+	clients   map[string]bool
+	latencies map[workload.OpType]*Latency
+	// settled tells whether the point has been returned by Settled.
+	settled bool
+}
+
+// Coordinator merges the batches pushed by the instances.
+type Coordinator struct {
+	mu        sync.Mutex
+	bucket    time.Duration
+	reporter  workload.Reporter
+	points    map[int64]*point
+	anomalies []workload.AnomalyEvent
+	clients   map[string]*Client
+}
+
+// New returns a Coordinator that merges intervals into buckets of the given
+// size. Anomalies and summaries are also passed to reporter as they arrive,
+// unless it is nil.
+func New(bucket time.Duration, reporter workload.Reporter) *Coordinator {
+	return &Coordinator{
+		bucket:   bucket,
+		reporter: reporter,
+		points:   map[int64]*point{},
+		clients:  map[string]*Client{},
+	}
+}
+
+// Push merges a batch of one instance.
+func (c *Coordinator) Push(b Batch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	client := c.clients[b.Client]
+	if client == nil {
+		client = &Client{Client: b.Client}
+		c.clients[b.Client] = client
+	}
+	client.LastPush = time.Now()
+
+	for _, e := range b.Intervals {
+		p := c.point(e.Time)
+		p.clients[b.Client] = true
+		p.Clients = len(p.clients)
+		p.Ops += e.Ops
+		p.OK += e.OK
+		p.Failed += e.Failed
+		p.Throughput = float64(p.OK) / c.bucket.Seconds()
+		if p.Ops > 0 {
+			p.Availability = float64(p.OK) / float64(p.Ops)
+		}
+	}
+	for _, e := range b.Latencies {
+		p := c.point(e.Time)
+		l := p.latencies[e.Op]
+		if l == nil {
+			l = &Latency{Op: e.Op}
+			p.latencies[e.Op] = l
+		}
+		l.Count += e.Count
+		l.P50Ms = max(l.P50Ms, e.P50Ms)
+		l.P99Ms = max(l.P99Ms, e.P99Ms)
+		l.MaxMs = max(l.MaxMs, e.MaxMs)
+	}
+	for _, e := range b.Anomalies {
+		e.Client = b.Client
+		c.anomalies = append(c.anomalies, e)
+		if c.reporter != nil {
+			c.reporter.Anomaly(e)
+		}
+	}
+	if b.Summary != nil {
+		s := *b.Summary
+		s.Client = b.Client
+		client.Summary = &s
+		if c.reporter != nil {
+			c.reporter.Summary(s)
+		}
+	}
+}
+
+func (c *Coordinator) point(ts time.Time) *point {
+	idx := ts.UnixNano() / int64(c.bucket)
+	p := c.points[idx]
+	if p == nil {
+		p = &point{
+			Point:     Point{Time: time.Unix(0, idx*int64(c.bucket))},
+			clients:   map[string]bool{},
+			latencies: map[workload.OpType]*Latency{},
+		}
+		c.points[idx] = p
+	}
+	return p
+}
+
+// export returns a copy of p with its latencies, sorted by operation type.
+func (p *point) export() Point {
+	out := p.Point
+	out.Latencies = nil
+	for _, l := range p.latencies {
+		out.Latencies = append(out.Latencies, *l)
+	}
+	sort.Slice(out.Latencies, func(i, j int) bool { return out.Latencies[i].Op < out.Latencies[j].Op })
+	return out
+}
+
+// Timeline returns the merged timeline so far, oldest bucket first.
+func (c *Coordinator) Timeline() Timeline {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := Timeline{
+		Bucket:    c.bucket.String(),
+		Points:    []Point{},
+		Anomalies: append([]workload.AnomalyEvent{}, c.anomalies...),
+		Clients:   []Client{},
+	}
+	for _, idx := range c.indexes() {
+		t.Points = append(t.Points, c.points[idx].export())
+	}
+	for _, name := range c.names() {
+		t.Clients = append(t.Clients, *c.clients[name])
+	}
+	return t
+}
+
+// Settled returns the points before the given time that have not been
+// returned yet, oldest first. Instances report a bucket only once its
+// writes have completed, so callers pass a time well in the past to print
+// every point once with the results of all instances.
+func (c *Coordinator) Settled(before time.Time) []Point {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var points []Point
+	for _, idx := range c.indexes() {
+		p := c.points[idx]
+		if p.settled || !p.Time.Before(before) {
+			continue
+		}
+		p.settled = true
+		points = append(points, p.export())
+	}
+	return points
+}
+
+// Finished returns the number of instances that pushed their summary.
+func (c *Coordinator) Finished() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	finished := 0
+	for _, client := range c.clients {
+		if client.Summary != nil {
+			finished++
+		}
+	}
+	return finished
+}
+
+// Summary sums up the instances. The cluster passes when every instance
+// finished and passed.
+func (c *Coordinator) Summary() ClusterSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := ClusterSummary{
+		Clients:   len(c.clients),
+		Anomalies: map[string]int{},
+		Passed:    true,
+	}
+	for _, p := range c.points {
+		s.Ops += p.Ops
+		s.OK += p.OK
+	}
+	if s.Ops > 0 {
+		s.Availability = float64(s.OK) / float64(s.Ops)
+	}
+	for _, e := range c.anomalies {
+		s.Anomalies[e.Kind]++
+	}
+	for _, name := range c.names() {
+		client := c.clients[name]
+		switch {
+		case client.Summary == nil:
+			s.Passed = false
+			s.Violations = append(s.Violations, fmt.Sprintf("%s did not finish", name))
+		case !client.Summary.Passed:
+			s.Finished++
+			s.Passed = false
+			s.Violations = append(s.Violations, fmt.Sprintf("%s failed", name))
+		default:
+			s.Finished++
+		}
+	}
+	return s
+}
+
+func (c *Coordinator) indexes() []int64 {
+	indexes := make([]int64, 0, len(c.points))
+	for idx := range c.points {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes
+}
+
+func (c *Coordinator) names() []string {
+	names := make([]string, 0, len(c.clients))
+	for name := range c.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handler serves the coordinator over HTTP: instances POST a Batch to
+// /push, and GET /timeline returns the Timeline.
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/push", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var b Batch
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if b.Client == "" {
+			http.Error(w, "missing client", http.StatusBadRequest)
+			return
+		}
+		c.Push(b)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/timeline", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Timeline())
+	})
+	return mux
+}
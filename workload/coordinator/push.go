@@ -0,0 +1,200 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+// Pusher is a workload.Reporter that pushes the intervals, latencies,
+// anomalies and summary of one instance to a coordinator over HTTP.
+// Operations and rates stay local.
+type Pusher struct {
+	url    string
+	client string
+	http   *http.Client
+
+	mu      sync.Mutex
+	pending Batch
+	// failing suppresses repeated errors while the coordinator is down.
+	failing bool
+
+	// push serializes the requests, so batches arrive in order.
+	push sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ workload.Reporter = &Pusher{}
+
+// NewPusher returns a Pusher that pushes to the coordinator at url, e.g.
+// http://coordinator:8080, every interval. Batches that cannot be pushed are
+// kept and retried with the next one.
+func NewPusher(url, client string, interval time.Duration) *Pusher {
+	p := &Pusher{
+		url:     strings.TrimSuffix(url, "/") + "/push",
+		client:  client,
+		http:    &http.Client{Timeout: 10 * time.Second},
+		pending: Batch{Client: client},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go p.loop(interval)
+	return p
+}
+
+func (p *Pusher) loop(interval time.Duration) {
+	defer close(p.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.flush()
+		}
+	}
+}
+
+// flush pushes the pending batch, or keeps it if the push fails.
+func (p *Pusher) flush() {
+	p.push.Lock()
+	defer p.push.Unlock()
+
+	p.mu.Lock()
+	b := p.pending
+	p.pending = Batch{Client: p.client}
+	p.mu.Unlock()
+	if len(b.Intervals) == 0 && len(b.Latencies) == 0 && len(b.Anomalies) == 0 && b.Summary == nil {
+		return
+	}
+
+	err := p.send(b)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		if p.failing {
+			fmt.Fprintf(os.Stderr, "Pushing to coordinator again\n")
+		}
+		p.failing = false
+		return
+	}
+	if !p.failing {
+		fmt.Fprintf(os.Stderr, "Error pushing to coordinator: %s\n", err)
+	}
+	p.failing = true
+	// A summary reported while the push was in flight is the newer one.
+	summary := p.pending.Summary
+	if summary == nil {
+		summary = b.Summary
+	}
+	p.pending = Batch{
+		Client:    p.client,
+		Intervals: append(b.Intervals, p.pending.Intervals...),
+		Latencies: append(b.Latencies, p.pending.Latencies...),
+		Anomalies: append(b.Anomalies, p.pending.Anomalies...),
+		Summary:   summary,
+	}
+}
+
+func (p *Pusher) send(b Batch) error {
+	body, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", p.url, resp.Status)
+	}
+	return nil
+}
+
+// Close stops the periodic pushes and pushes what is still pending.
+func (p *Pusher) Close() error {
+	close(p.stop)
+	<-p.done
+	p.flush()
+	return nil
+}
+
+func (p *Pusher) Operation(workload.OperationEvent) {}
+
+func (p *Pusher) Rate(workload.RateEvent) {}
+
+func (p *Pusher) Interval(e workload.IntervalEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending.Intervals = append(p.pending.Intervals, e)
+}
+
+func (p *Pusher) Latency(e workload.LatencyEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending.Latencies = append(p.pending.Latencies, e)
+}
+
+func (p *Pusher) Anomaly(e workload.AnomalyEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending.Anomalies = append(p.pending.Anomalies, e)
+}
+
+// Summary pushes right away, the run is over.
+func (p *Pusher) Summary(s workload.Summary) {
+	p.mu.Lock()
+	p.pending.Summary = &s
+	p.mu.Unlock()
+	p.flush()
+}
+
+// Reporter returns a workload.Reporter that pushes to c in-process, a
+// stand-in for a Pusher and the HTTP server when all instances run in one
+// process, e.g. in tests.
+func (c *Coordinator) Reporter(client string) workload.Reporter {
+	return &local{c: c, client: client}
+}
+
+type local struct {
+	c      *Coordinator
+	client string
+}
+
+func (l *local) Operation(workload.OperationEvent) {}
+
+func (l *local) Rate(workload.RateEvent) {}
+
+func (l *local) Interval(e workload.IntervalEvent) {
+	l.c.Push(Batch{Client: l.client, Intervals: []workload.IntervalEvent{e}})
+}
+
+func (l *local) Latency(e workload.LatencyEvent) {
+	l.c.Push(Batch{Client: l.client, Latencies: []workload.LatencyEvent{e}})
+}
+
+func (l *local) Anomaly(e workload.AnomalyEvent) {
+	l.c.Push(Batch{Client: l.client, Anomalies: []workload.AnomalyEvent{e}})
+}
+
+func (l *local) Summary(s workload.Summary) {
+	l.c.Push(Batch{Client: l.client, Summary: &s})
+}
@@ -0,0 +1,176 @@
+package workload
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Distribution picks the key every write goes to among the keys of an
+// instance. Key must be a pure function of the sequence, so a restarted
+// workload knows the keys of the writes that may have been issued after its
+// last checkpoint.
+type Distribution interface {
+	Key(sequence int) int
+}
+
+// Sequential writes the keys round-robin.
+type Sequential struct {
+	Keys int
+}
+
+func (d Sequential) Key(sequence int) int { return sequence % d.Keys }
+
+// Uniform picks every key with the same probability.
+type Uniform struct {
+	Keys int
+	Seed int64
+}
+
+func (d Uniform) Key(sequence int) int {
+	return pick(random(d.Seed, sequence), d.Keys)
+}
+
+// Hotspot sends the fraction Ops of the writes to the first fraction
+// HotKeys of the keys, and the rest to the others, uniformly within both.
+type Hotspot struct {
+	Keys    int
+	Seed    int64
+	HotKeys float64
+	Ops     float64
+}
+
+func (d Hotspot) Key(sequence int) int {
+	hot := max(1, min(d.Keys, int(d.HotKeys*float64(d.Keys))))
+	h := random(d.Seed, sequence)
+	u := uniform(mix(h))
+	if uniform(h) < d.Ops || hot == d.Keys {
+		return int(u * float64(hot))
+	}
+	return hot + int(u*float64(d.Keys-hot))
+}
+
+// Zipfian picks key i with a probability proportional to 1/(i+1)^theta, so
+// the first keys are hot. It follows the generator of YCSB.
+type Zipfian struct {
+	keys  int
+	seed  int64
+	theta float64
+	alpha float64
+	zetan float64
+	eta   float64
+}
+
+// NewZipfian precomputes the constants of a zipfian distribution over keys
+// with the skew theta, which must be in (0, 1); YCSB uses 0.99.
+func NewZipfian(keys int, seed int64, theta float64) *Zipfian {
+	zeta := func(n int) float64 {
+		sum := 0.0
+		for i := 1; i <= n; i++ {
+			sum += 1 / math.Pow(float64(i), theta)
+		}
+		return sum
+	}
+	z := &Zipfian{keys: keys, seed: seed, theta: theta, alpha: 1 / (1 - theta), zetan: zeta(keys)}
+	z.eta = (1 - math.Pow(2/float64(keys), 1-theta)) / (1 - zeta(2)/z.zetan)
+	return z
+}
+
+func (z *Zipfian) Key(sequence int) int {
+	return z.rank(uniform(random(z.seed, sequence)))
+}
+
+// rank maps a uniform u in [0, 1) to a zipfian rank in [0, keys).
+func (z *Zipfian) rank(u float64) int {
+	uz := u * z.zetan
+	switch {
+	case uz < 1:
+		return 0
+	case uz < 1+math.Pow(0.5, z.theta):
+		return min(1, z.keys-1)
+	}
+	return min(z.keys-1, int(float64(z.keys)*math.Pow(z.eta*u-z.eta+1, z.alpha)))
+}
+
+// Latest favors the keys written most recently: the newest key moves through
+// the keys round-robin, like Sequential, and a write goes to a key a zipfian
+// distance behind it.
+type Latest struct {
+	*Zipfian
+}
+
+func (d Latest) Key(sequence int) int {
+	newest := sequence % d.keys
+	return (newest - d.Zipfian.Key(sequence) + d.keys) % d.keys
+}
+
+// ParseDistribution parses a key distribution spec over keys keys, of the
+// form "<name>:<key>=<value>,...":
+//
+//	sequential
+//	uniform
+//	zipfian:theta=0.99
+//	hotspot:keys=0.2,ops=0.8
+//	latest:theta=0.99
+//
+// The parameters are optional and default to the values shown. The random
+// distributions pick the same keys for the same seed.
+func ParseDistribution(spec string, keys int, seed int64) (Distribution, error) {
+	name, p, err := parseSpec("key distribution", spec)
+	if err != nil {
+		return nil, err
+	}
+	var d Distribution
+	switch name {
+	case "sequential":
+		d = Sequential{Keys: keys}
+	case "uniform":
+		d = Uniform{Keys: keys, Seed: seed}
+	case "zipfian":
+		d = NewZipfian(keys, seed, p.fraction("theta", 0.99))
+	case "hotspot":
+		d = Hotspot{Keys: keys, Seed: seed, HotKeys: p.fraction("keys", 0.2), Ops: p.fraction("ops", 0.8)}
+	case "latest":
+		d = Latest{NewZipfian(keys, seed, p.fraction("theta", 0.99))}
+	default:
+		return nil, fmt.Errorf("key distribution %q: unknown distribution %q", spec, name)
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// fraction returns the optional parameter key, which must be in (0, 1), or
+// def if it is not set.
+func (p *specParams) fraction(key string, def float64) float64 {
+	if _, ok := p.lookup(key, false); !ok {
+		return def
+	}
+	f := p.float(key)
+	if (f <= 0 || f >= 1) && p.err == nil {
+		p.err = fmt.Errorf("%s %q: %s must be between 0 and 1", p.kind, p.spec, key)
+	}
+	return f
+}
+
+// random hashes seed and sequence into 64 random bits.
+func random(seed int64, sequence int) uint64 {
+	return mix(uint64(seed)*0x9e3779b97f4a7c15 + uint64(sequence))
+}
+
+// mix is the finalizer of splitmix64.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// uniform maps random bits to [0, 1).
+func uniform(h uint64) float64 {
+	return float64(h>>11) / (1 << 53)
+}
+
+func pick(h uint64, n int) int {
+	return int(uniform(h) * float64(n))
+}
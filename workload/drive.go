@@ -0,0 +1,129 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loop is what drive issues and runs alongside. The hooks are nil but for
+// the register workload, which leases sequences and orders the writes of a
+// key.
+type loop struct {
+	// first is the sequence of the first operation.
+	first int
+	// do runs o on the pool worker.
+	do func(ctx context.Context, worker int, o op) Result
+	// background run until every operation completed, e.g. a checker.
+	background []func(ctx context.Context)
+	// issue fills in o, its ts and sequence set, before it is submitted. An
+	// error stops issuing and is returned by drive.
+	issue func(o *op) error
+	// submitted is called with o once the pool accepted or dropped it.
+	submitted func(o op, accepted bool)
+}
+
+// drive issues the operations of l at the scheduled rate until ctx is
+// cancelled or the Duration or Operations limit is reached, with the
+// background tasks of l running meanwhile. It reports the buckets, rates and
+// latencies, and returns when every operation completed, with the start of
+// the schedule and the number of dropped operations. Every pool worker gets
+// a history process of its own, from the first one the history does not use
+// yet.
+func (w *Workload) drive(ctx context.Context, l loop) (time.Time, int64, error) {
+	process := 0
+	if w.cfg.History != nil {
+		process = w.cfg.History.NextProcess()
+	}
+	w.processes = make([]int, w.cfg.MaxInFlight+1)
+	for i := range w.processes {
+		w.processes[i] = process + i
+	}
+
+	// Operations outlive ctx so a shutdown can drain and check.
+	opCtx := context.WithoutCancel(ctx)
+	checkCtx, stopCheck := context.WithCancel(opCtx)
+	defer stopCheck()
+	var background sync.WaitGroup
+
+	output := make(chan Result, w.cfg.MaxInFlight)
+	consumed := make(chan struct{})
+	go func() {
+		w.consume(output, time.Now())
+		close(consumed)
+	}()
+	for _, task := range l.background {
+		background.Add(1)
+		go func(task func(context.Context)) {
+			defer background.Done()
+			task(checkCtx)
+		}(task)
+	}
+
+	issueCtx := ctx
+	if w.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		issueCtx, cancel = context.WithTimeout(ctx, w.cfg.Duration)
+		defer cancel()
+	}
+
+	schedule := w.cfg.Schedule
+	if schedule == nil {
+		schedule = Constant(w.cfg.Rate)
+	}
+	pc := newPacer(schedule)
+	pl := newPool(w.cfg.MaxInFlight, w.cfg.QueueSize, func(worker int, o op) {
+		inFlight.WithLabelValues(w.cfg.Backend).Inc()
+		defer inFlight.WithLabelValues(w.cfg.Backend).Dec()
+		output <- l.do(opCtx, worker, o)
+	})
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		w.report(checkCtx, pc, pl)
+	}()
+
+	var issueErr error
+	for sequence := l.first; w.cfg.Operations == 0 || sequence < w.cfg.Operations; {
+		ts, err := pc.wait(issueCtx)
+		if err != nil {
+			break
+		}
+		o := op{ts: ts, sequence: sequence}
+		if l.issue != nil {
+			if issueErr = l.issue(&o); issueErr != nil {
+				break
+			}
+		}
+		accepted := pl.submit(o)
+		if l.submitted != nil {
+			l.submitted(o, accepted)
+		}
+		if accepted {
+			sequence++
+		} else {
+			droppedTotal.WithLabelValues(w.cfg.Backend).Inc()
+		}
+	}
+
+	pl.close()
+	close(output)
+	<-consumed
+	stopCheck()
+	background.Wait()
+	return pc.start, pl.droppedTotal.Load(), issueErr
+}
+
+// finish checks summary against the thresholds and reports it. It returns
+// ErrThresholdViolated if the run did not pass.
+func (w *Workload) finish(summary Summary) error {
+	summary.Violations = w.cfg.Thresholds.violations(summary)
+	summary.Passed = len(summary.Violations) == 0
+	w.reporter.Summary(summary)
+	if !summary.Passed {
+		return fmt.Errorf("%w: %s", ErrThresholdViolated, strings.Join(summary.Violations, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+var (
+	// ErrNotFound is returned by Driver.Read when the key has never been written.
+	ErrNotFound = errors.New("key not found")
+	// ErrReadUnsupported is returned by Driver.Read for backends that cannot read
+	// back what was written, e.g. message queues. The checker is skipped for them.
+	ErrReadUnsupported = errors.New("read not supported by driver")
+	// ErrExists is returned by Inserter.Insert when the key already exists.
+	ErrExists = errors.New("key exists")
+)
+
+// Driver is implemented by every backend the workload runs against.
+//
+// Keys are small integers and values grow monotonically per key, starting
+// from 0 for the first write to a key.
+type Driver interface {
+	// Setup prepares the backend, e.g. by (re)creating the table.
+	Setup(ctx context.Context) error
+	// Write stores value under key, whether the key exists or not.
+	Write(ctx context.Context, key, value int) error
+	// Read returns the value currently stored under key.
+	Read(ctx context.Context, key int) (int, error)
+	// Close releases the connection to the backend.
+	Close() error
+}
+
+// Inserter is implemented by drivers that can insert a key that must not
+// exist yet. Insert returns ErrExists if it does.
+type Inserter interface {
+	Insert(ctx context.Context, key, value int) error
+}
+
+// Updater is implemented by drivers that can update a key that must exist.
+// Update returns ErrNotFound if it does not.
+type Updater interface {
+	Update(ctx context.Context, key, value int) error
+}
+
+// Deleter is implemented by drivers that can delete a key. Deleting a key
+// that does not exist succeeds.
+type Deleter interface {
+	Delete(ctx context.Context, key int) error
+}
+
+// Scanner is implemented by drivers that can read a range of keys at once.
+// Scan returns the keys in [from, to) that exist, with their values.
+type Scanner interface {
+	Scan(ctx context.Context, from, to int) ([]KeyValue, error)
+}
+
+// ErrorClassifier is implemented by drivers that can tell a failed write that
+// may still have been applied from one that definitely was not.
+type ErrorClassifier interface {
+	Indeterminate(err error) bool
+}
+
+// classify maps the error of a write to its history outcome. Timeouts and
+// broken connections are indeterminate: the write may have been applied.
+func classify(d Driver, err error) history.Outcome {
+	if err == nil {
+		return history.OK
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return history.Info
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return history.Info
+	}
+	if c, ok := d.(ErrorClassifier); ok && c.Indeterminate(err) {
+		return history.Info
+	}
+	return history.Fail
+}
@@ -0,0 +1,37 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+func GetEnvWithDefault(key, fallback string) string {
+	value := os.Getenv(key)
+	if len(value) == 0 {
+		return fallback
+	}
+	return value
+}
+
+// WaitForHost blocks until host resolves, so the workload can be started
+// before the service of the system under test exists. It returns ctx.Err()
+// if ctx is done first, e.g. on a signal.
+func WaitForHost(ctx context.Context, host string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if _, err := net.LookupIP(host); err != nil {
+			fmt.Fprintf(os.Stderr, "Waiting for SVC nslookup: %s\n", err)
+			continue
+		}
+		return nil
+	}
+}
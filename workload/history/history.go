@@ -0,0 +1,254 @@
+// Package history records every operation a workload issues so correctness
+// can be analyzed offline.
+//
+// A history file holds one JSON object per line and per completed operation,
+// in the order the operations completed:
+//
+//	{"index":0,"process":3,"client":"writer-0","f":"write","key":7,"value":2,
+//	 "outcome":"ok","invoke":"...","complete":"..."}
+//
+// The outcome is "ok" when the operation took effect, "fail" when it
+// definitely did not, and "info" when it is unknown, e.g. a write that timed
+// out and may or may not have been applied. A process issues one operation at
+// a time; after an info outcome the process is never reused, so every
+// process's operations are sequential.
+//
+// A transaction is recorded as one operation of f "txn" whose micro-operations
+// are listed in order under mops, e.g. an append and a read of a list:
+//
+//	{"index":5,"process":1,"f":"txn","key":0,"value":null,"mops":[
+//	 {"f":"append","key":3,"value":12},{"f":"read","key":4,"list":[2,9]}],...}
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Func is the kind of operation.
+type Func string
+
+const (
+	Write Func = "write"
+	Read  Func = "read"
+	// Delete removes the key. Its value orders it among the writes of the
+	// key, no read returns it.
+	Delete Func = "delete"
+	// Txn is a transaction, its micro-operations are in Op.Mops.
+	Txn Func = "txn"
+	// Append adds a value to the end of the list under a key. It is only
+	// a micro-operation of a transaction.
+	Append Func = "append"
+	// Add adds its value to a set, and ReadSet reads the whole set into
+	// Op.Set.
+	Add     Func = "add"
+	ReadSet Func = "read-set"
+)
+
+// Outcome is how an operation completed.
+type Outcome string
+
+const (
+	OK   Outcome = "ok"
+	Fail Outcome = "fail"
+	Info Outcome = "info"
+)
+
+// Op is one operation of a history.
+type Op struct {
+	Index   int    `json:"index"`
+	Process int    `json:"process"`
+	Client  string `json:"client,omitempty"`
+	F       Func   `json:"f"`
+	Key     int    `json:"key"`
+	// Value is the value written, or the value returned by an ok read. It is
+	// nil for reads that failed or found no value.
+	Value    *int      `json:"value"`
+	Outcome  Outcome   `json:"outcome"`
+	Invoke   time.Time `json:"invoke"`
+	Complete time.Time `json:"complete"`
+	Error    string    `json:"error,omitempty"`
+	// Mops are the micro-operations of a transaction, in the order it ran
+	// them.
+	Mops []Mop `json:"mops,omitempty"`
+	// Set holds the elements an ok read-set returned.
+	Set []int `json:"set,omitempty"`
+}
+
+// Mop is a micro-operation of a transaction: an append of Value to the list
+// under Key, or a read of it.
+type Mop struct {
+	F     Func `json:"f"`
+	Key   int  `json:"key"`
+	Value *int `json:"value,omitempty"`
+	// List is the list a read returned. What the reads of a transaction
+	// that did not complete ok returned is unknown.
+	List []int `json:"list,omitempty"`
+}
+
+func (m Mop) String() string {
+	if m.F == Append {
+		return fmt.Sprintf("append %d to %d", *m.Value, m.Key)
+	}
+	return fmt.Sprintf("read %d = %v", m.Key, m.List)
+}
+
+func (o Op) String() string {
+	if o.F == Txn {
+		mops := make([]string, len(o.Mops))
+		for i, m := range o.Mops {
+			mops[i] = m.String()
+			if m.F == Read && o.Outcome != OK {
+				mops[i] = fmt.Sprintf("read %d", m.Key)
+			}
+		}
+		return fmt.Sprintf("#%d process %d txn [%s]: %s [%s, %s]",
+			o.Index, o.Process, strings.Join(mops, ", "), o.Outcome,
+			o.Invoke.Format(time.RFC3339Nano), o.Complete.Format(time.RFC3339Nano))
+	}
+	if o.F == ReadSet {
+		return fmt.Sprintf("#%d process %d read-set of %d elements: %s [%s, %s]",
+			o.Index, o.Process, len(o.Set), o.Outcome,
+			o.Invoke.Format(time.RFC3339Nano), o.Complete.Format(time.RFC3339Nano))
+	}
+	value := "nil"
+	if o.Value != nil {
+		value = fmt.Sprint(*o.Value)
+	}
+	return fmt.Sprintf("#%d process %d %s key %d value %s: %s [%s, %s]",
+		o.Index, o.Process, o.F, o.Key, value, o.Outcome,
+		o.Invoke.Format(time.RFC3339Nano), o.Complete.Format(time.RFC3339Nano))
+}
+
+// IntPtr returns a pointer to v, for filling in Op.Value.
+func IntPtr(v int) *int {
+	return &v
+}
+
+// Recorder appends operations to a history file.
+type Recorder struct {
+	mu    sync.Mutex
+	path  string
+	f     *os.File
+	w     *bufio.Writer
+	enc   *json.Encoder
+	index int
+	// process is the lowest process id not used in the history yet.
+	process int
+}
+
+// NewRecorder starts a new history at path, replacing any existing one.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &Recorder{path: path, f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+// OpenRecorder appends to the history at path, creating it if needed, so a
+// restarted workload continues the history of the previous one. Indices
+// continue where the history ends.
+func OpenRecorder(path string) (*Recorder, error) {
+	ops, err := Load(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	r := &Recorder{path: path, f: f, w: w, enc: json.NewEncoder(w)}
+	for _, op := range ops {
+		if op.Index >= r.index {
+			r.index = op.Index + 1
+		}
+		if op.Process >= r.process {
+			r.process = op.Process + 1
+		}
+	}
+	return r, nil
+}
+
+// NextProcess returns the lowest process id the history does not use yet.
+// Processes of a restarted workload start there.
+func (r *Recorder) NextProcess() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.process
+}
+
+// Record assigns the next index to op and appends it to the history.
+func (r *Recorder) Record(op Op) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op.Index = r.index
+	r.index++
+	if op.Process >= r.process {
+		r.process = op.Process + 1
+	}
+	return r.enc.Encode(op)
+}
+
+// Ops flushes the history and reads back everything recorded so far.
+func (r *Recorder) Ops() ([]Op, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil {
+		return nil, err
+	}
+	return Load(r.path)
+}
+
+// Close flushes the history and closes the file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// Load reads a history file, sorted by invocation time.
+func Load(path string) ([]Op, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a history, sorted by invocation time.
+func Parse(r io.Reader) ([]Op, error) {
+	var ops []Op
+	dec := json.NewDecoder(r)
+	for {
+		var op Op
+		err := dec.Decode(&op)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("history entry %d: %w", len(ops), err)
+		}
+		ops = append(ops, op)
+	}
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].Invoke.Before(ops[j].Invoke)
+	})
+	return ops, nil
+}
@@ -0,0 +1,283 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IsolationLevel is the isolation level of the transactions of the isolation
+// suite.
+type IsolationLevel string
+
+const (
+	ReadUncommitted IsolationLevel = "read-uncommitted"
+	ReadCommitted   IsolationLevel = "read-committed"
+	RepeatableRead  IsolationLevel = "repeatable-read"
+	Serializable    IsolationLevel = "serializable"
+)
+
+// The anomalies the isolation suite provokes.
+const (
+	// AnomalyDirtyRead is a read of a write that was rolled back.
+	AnomalyDirtyRead = "dirty-read"
+	// AnomalyG1c is circular information flow: two transactions that each
+	// read what the other wrote.
+	AnomalyG1c = "g1c"
+	// AnomalyLostUpdate is two transactions that read and increment a
+	// value and both commit, but the value grew by one.
+	AnomalyLostUpdate = "lost-update"
+	// AnomalyReadSkew is a transaction that reads two values, one before
+	// and one after another transaction changed both.
+	AnomalyReadSkew = "read-skew"
+	// AnomalyWriteSkew is two transactions that each check a constraint
+	// over two values and write one of them, so both commit and break it.
+	AnomalyWriteSkew = "write-skew"
+)
+
+// IsolationAnomalies are the anomalies of the isolation suite, weakest
+// first.
+var IsolationAnomalies = []string{AnomalyDirtyRead, AnomalyG1c, AnomalyLostUpdate, AnomalyReadSkew, AnomalyWriteSkew}
+
+// isolationPrevents holds the anomalies every level promises to prevent,
+// following the definitions of ANSI SQL as generalized by Berenson et al.
+// and Adya. Snapshot isolation, which several databases call repeatable
+// read, also prevents what repeatable read does.
+var isolationPrevents = map[IsolationLevel][]string{
+	ReadUncommitted: nil,
+	ReadCommitted:   {AnomalyDirtyRead, AnomalyG1c},
+	RepeatableRead:  {AnomalyDirtyRead, AnomalyG1c, AnomalyLostUpdate, AnomalyReadSkew},
+	Serializable:    IsolationAnomalies,
+}
+
+// ParseIsolationLevel parses one of the isolation levels.
+func ParseIsolationLevel(s string) (IsolationLevel, error) {
+	level := IsolationLevel(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := isolationPrevents[level]; !ok {
+		return "", fmt.Errorf("unknown isolation level %q", s)
+	}
+	return level, nil
+}
+
+// Prevents reports whether level promises to prevent anomaly.
+func (level IsolationLevel) Prevents(anomaly string) bool {
+	return contains(isolationPrevents[level], anomaly)
+}
+
+// Promises returns the anomalies level promises to prevent by the standard
+// definitions, which backends that promise less at the level narrow down.
+func (level IsolationLevel) Promises() []string {
+	return append([]string(nil), isolationPrevents[level]...)
+}
+
+func contains(anomalies []string, anomaly string) bool {
+	for _, a := range anomalies {
+		if a == anomaly {
+			return true
+		}
+	}
+	return false
+}
+
+// Trial is the outcome of one attempt to provoke an anomaly.
+type Trial struct {
+	// Observed tells whether the anomaly happened, and Detail describes
+	// what was read.
+	Observed bool
+	Detail   string
+	// Aborted tells whether the database aborted one of the transactions,
+	// e.g. on a deadlock or a serialization failure, which prevents the
+	// anomaly.
+	Aborted bool
+}
+
+// IsolationDriver is implemented by backends with transactions that can run
+// the isolation suite.
+type IsolationDriver interface {
+	Driver
+	// IsolationPromises returns the anomalies the backend prevents at
+	// level, which may be fewer than level.Promises() where the backend
+	// implements the level more weakly. It returns an error if the
+	// backend does not run transactions at level, e.g. because it rejects
+	// the level.
+	IsolationPromises(ctx context.Context, level IsolationLevel) ([]string, error)
+	// SetupIsolation (re)creates the rows the trials run on.
+	SetupIsolation(ctx context.Context) error
+	// Trial provokes anomaly once with transactions at level, on rows of
+	// its own identified by id. It returns an error if the trial could
+	// not run, e.g. because the connection failed.
+	Trial(ctx context.Context, anomaly string, level IsolationLevel, id int) (Trial, error)
+}
+
+// IsolationConfig configures the isolation suite on top of the Config shared
+// with the register workload.
+type IsolationConfig struct {
+	Level IsolationLevel
+	// Anomalies are the anomalies provoked in turn.
+	Anomalies []string
+}
+
+func DefaultIsolationConfig() IsolationConfig {
+	return IsolationConfig{
+		Level:     RepeatableRead,
+		Anomalies: IsolationAnomalies,
+	}
+}
+
+func (c IsolationConfig) Validate() error {
+	if _, ok := isolationPrevents[c.Level]; !ok {
+		return fmt.Errorf("unknown isolation level %q", c.Level)
+	}
+	if len(c.Anomalies) == 0 {
+		return fmt.Errorf("no anomaly to provoke")
+	}
+	for _, anomaly := range c.Anomalies {
+		known := false
+		for _, a := range IsolationAnomalies {
+			known = known || a == anomaly
+		}
+		if !known {
+			return fmt.Errorf("unknown anomaly %q, want one of %s", anomaly, strings.Join(IsolationAnomalies, ", "))
+		}
+	}
+	return nil
+}
+
+// IsolationReport summarizes the trials of the isolation suite.
+type IsolationReport struct {
+	Level   IsolationLevel    `json:"level"`
+	Results []IsolationResult `json:"results"`
+}
+
+// IsolationResult counts the trials of one anomaly. The level keeps its
+// promise unless the anomaly is Prevented but was Observed.
+type IsolationResult struct {
+	Anomaly   string `json:"anomaly"`
+	Prevented bool   `json:"prevented"`
+	Trials    int    `json:"trials"`
+	Observed  int    `json:"observed"`
+	Aborted   int    `json:"aborted"`
+	Failed    int    `json:"failed"`
+	Kept      bool   `json:"kept"`
+}
+
+// Isolation provokes the anomalies in turn at the scheduled rate, one trial
+// per operation, and reports whether what it observed matches what the
+// isolation level promises. An anomaly the level prevents but that was
+// observed is reported as an anomaly of its name. What the level promises is
+// what the backend promises at it, which may be less than the standard.
+type Isolation struct {
+	w      *Workload
+	driver IsolationDriver
+	cfg    IsolationConfig
+
+	mu      sync.Mutex
+	results map[string]*IsolationResult
+}
+
+// NewIsolation returns the isolation suite. Of cfg, it uses the settings of
+// the load, its reporting and thresholds and the instance; the settings of
+// the keys and their checks do not apply.
+func NewIsolation(driver IsolationDriver, cfg Config, isolation IsolationConfig) *Isolation {
+	results := map[string]*IsolationResult{}
+	for _, anomaly := range isolation.Anomalies {
+		results[anomaly] = &IsolationResult{Anomaly: anomaly, Prevented: isolation.Level.Prevents(anomaly)}
+	}
+	return &Isolation{
+		w:       New(driver, cfg),
+		driver:  driver,
+		cfg:     isolation,
+		results: results,
+	}
+}
+
+func (s *Isolation) Run(ctx context.Context) error {
+	w := s.w
+	if err := w.cfg.Validate(); err != nil {
+		return err
+	}
+	if err := s.cfg.Validate(); err != nil {
+		return err
+	}
+	promises, err := s.driver.IsolationPromises(ctx, s.cfg.Level)
+	if err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
+	// Trials are only checked against what the backend promises, so a
+	// weaker level than the standard one is not reported as violated.
+	for anomaly, result := range s.results {
+		result.Prevented = contains(promises, anomaly)
+	}
+	// Only the first instance sets up the rows for all of them.
+	if w.cfg.Instance == 0 {
+		if err := s.driver.SetupIsolation(ctx); err != nil {
+			return fmt.Errorf("setup: %w", err)
+		}
+	}
+	start, dropped, err := w.drive(ctx, loop{do: s.do})
+	if err != nil {
+		return err
+	}
+	return w.finish(w.tally.summary(Summary{
+		Backend:   w.cfg.Backend,
+		Client:    w.cfg.Client,
+		Start:     start,
+		End:       time.Now(),
+		Dropped:   dropped,
+		Isolation: s.summary(),
+	}))
+}
+
+// do runs the trial of o, of the anomalies in turn.
+func (s *Isolation) do(ctx context.Context, _ int, o op) Result {
+	w := s.w
+	anomaly := s.cfg.Anomalies[o.sequence%len(s.cfg.Anomalies)]
+	// The instances run their trials on rows of their own.
+	id := o.sequence*w.cfg.Instances + w.cfg.Instance
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	trial, err := s.driver.Trial(ctx, anomaly, s.cfg.Level, id)
+	w.measure(OpTrial, id, nil, start, time.Now(), classify(s.driver, err), err)
+
+	s.mu.Lock()
+	result := s.results[anomaly]
+	result.Trials++
+	switch {
+	case err != nil:
+		result.Failed++
+	case trial.Observed:
+		result.Observed++
+	case trial.Aborted:
+		result.Aborted++
+	}
+	s.mu.Unlock()
+
+	if err == nil && trial.Observed && result.Prevented {
+		inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+		w.tally.anomaly(anomaly)
+		w.reporter.Anomaly(AnomalyEvent{
+			Time:    time.Now(),
+			Kind:    anomaly,
+			Key:     id,
+			Message: fmt.Sprintf("%s under %s, which prevents it: %s", anomaly, s.cfg.Level, trial.Detail),
+		})
+	}
+	return Result{err: err, ts: o.ts}
+}
+
+func (s *Isolation) summary() *IsolationReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := &IsolationReport{Level: s.cfg.Level}
+	for _, anomaly := range IsolationAnomalies {
+		if result, ok := s.results[anomaly]; ok {
+			r := *result
+			r.Kept = !r.Prevented || r.Observed == 0
+			report.Results = append(report.Results, r)
+		}
+	}
+	return report
+}
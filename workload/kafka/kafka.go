@@ -0,0 +1,112 @@
+// Package kafka implements the workload driver for Kafka. Every write is
+// produced as one message; the value of the message is the write's sequence.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+type Config struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Topic    string
+}
+
+type Driver struct {
+	producer *ckafka.Producer
+	// config is the configuration of the producer, which the consumers of
+	// the set workload share.
+	config   ckafka.ConfigMap
+	topic    string
+	keySpace int
+
+	mu sync.Mutex
+	// setStart holds the offset of every partition the sets start at.
+	setStart map[int32]int64
+}
+
+var _ workload.Driver = &Driver{}
+
+// NewDriver creates a producer. keySpace is needed to turn a key and value
+// into the number carried by the message, unique to every write of an
+// instance.
+func NewDriver(cfg Config, keySpace int) (*Driver, error) {
+	config := ckafka.ConfigMap{
+		"bootstrap.servers": fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		"security.protocol": "sasl_plaintext",
+		"sasl.mechanisms":   "SCRAM-SHA-512",
+		"sasl.username":     cfg.User,
+		"sasl.password":     cfg.Password,
+	}
+	p, err := ckafka.NewProducer(with(config, ckafka.ConfigMap{
+		"client.id": "myProducer",
+		"acks":      "all",
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{producer: p, config: config, topic: cfg.Topic, keySpace: keySpace}, nil
+}
+
+func (d *Driver) Setup(ctx context.Context) error {
+	return nil
+}
+
+// with returns a copy of config with the settings of extra.
+func with(config, extra ckafka.ConfigMap) *ckafka.ConfigMap {
+	merged := ckafka.ConfigMap{}
+	for k, v := range config {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return &merged
+}
+
+// Write produces a message and waits for its delivery report.
+func (d *Driver) Write(ctx context.Context, key, value int) error {
+	return d.produce(ctx, nil, fmt.Sprintf("%d", value*d.keySpace+key%d.keySpace))
+}
+
+// produce produces a message with key and value and waits for its delivery
+// report.
+func (d *Driver) produce(ctx context.Context, key []byte, value string) error {
+	delivery := make(chan ckafka.Event, 1)
+	err := d.producer.Produce(&ckafka.Message{
+		TopicPartition: ckafka.TopicPartition{Topic: &d.topic, Partition: ckafka.PartitionAny},
+		Key:            key,
+		Value:          []byte(value)},
+		delivery,
+	)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case e := <-delivery:
+		if m, ok := e.(*ckafka.Message); ok {
+			return m.TopicPartition.Error
+		}
+		return fmt.Errorf("unexpected delivery event: %s", e)
+	}
+}
+
+func (d *Driver) Read(ctx context.Context, key int) (int, error) {
+	return 0, workload.ErrReadUnsupported
+}
+
+func (d *Driver) Close() error {
+	d.producer.Close()
+	return nil
+}
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+var _ workload.SetDriver = &Driver{}
+
+// defaultTimeout bounds the requests of a context without a deadline.
+const defaultTimeout = 10 * time.Second
+
+// setKey is the key of the messages of set. The sets share the topic, with
+// whatever else was produced to it.
+func setKey(set int) []byte {
+	return []byte(fmt.Sprintf("set-%d", set))
+}
+
+// timeoutMs returns the time left until the deadline of ctx.
+func timeoutMs(ctx context.Context) int {
+	if deadline, ok := ctx.Deadline(); ok {
+		return max(1, int(time.Until(deadline).Milliseconds()))
+	}
+	return int(defaultTimeout.Milliseconds())
+}
+
+// watermarks returns the offset after the last message of every partition of
+// the topic, none if the topic does not exist yet.
+func (d *Driver) watermarks(ctx context.Context, client interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*ckafka.Metadata, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+}) (map[int32]int64, error) {
+	metadata, err := client.GetMetadata(&d.topic, false, timeoutMs(ctx))
+	if err != nil {
+		return nil, err
+	}
+	high := map[int32]int64{}
+	for _, partition := range metadata.Topics[d.topic].Partitions {
+		_, offset, err := client.QueryWatermarkOffsets(d.topic, partition.ID, timeoutMs(ctx))
+		if err != nil {
+			return nil, err
+		}
+		high[partition.ID] = offset
+	}
+	return high, nil
+}
+
+// SetupSet remembers where every partition of the topic ends, since Kafka
+// cannot empty a topic: the sets start there.
+func (d *Driver) SetupSet(ctx context.Context, set int) error {
+	start, err := d.watermarks(ctx, d.producer)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.setStart = start
+	d.mu.Unlock()
+	return nil
+}
+
+// Add produces element as a message keyed by set.
+func (d *Driver) Add(ctx context.Context, set, element int) error {
+	return d.produce(ctx, setKey(set), strconv.Itoa(element))
+}
+
+// ReadSet consumes the topic from where the sets start up to where every
+// partition ends now, without committing offsets, and returns the elements
+// of the messages of set.
+func (d *Driver) ReadSet(ctx context.Context, set int) ([]int, error) {
+	consumer, err := ckafka.NewConsumer(with(d.config, ckafka.ConfigMap{
+		"group.id":           fmt.Sprintf("workloads-set-%d-%d", set, time.Now().UnixNano()),
+		"enable.auto.commit": false,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	defer consumer.Close()
+
+	end, err := d.watermarks(ctx, consumer)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	var assigned []ckafka.TopicPartition
+	for partition, high := range end {
+		offset, ok := d.setStart[partition]
+		if !ok {
+			offset = 0
+		}
+		if offset < high {
+			assigned = append(assigned, ckafka.TopicPartition{Topic: &d.topic, Partition: partition, Offset: ckafka.Offset(offset)})
+		}
+	}
+	d.mu.Unlock()
+	if err := consumer.Assign(assigned); err != nil {
+		return nil, err
+	}
+
+	key := string(setKey(set))
+	var elements []int
+	remaining := map[int32]bool{}
+	for _, tp := range assigned {
+		remaining[tp.Partition] = true
+	}
+	for len(remaining) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		switch e := consumer.Poll(100).(type) {
+		case *ckafka.Message:
+			if e.TopicPartition.Error != nil {
+				return nil, e.TopicPartition.Error
+			}
+			if string(e.Key) == key {
+				element, err := strconv.Atoi(string(e.Value))
+				if err != nil {
+					return nil, fmt.Errorf("message at offset %s: %w", e.TopicPartition.Offset, err)
+				}
+				elements = append(elements, element)
+			}
+			if int64(e.TopicPartition.Offset)+1 >= end[e.TopicPartition.Partition] {
+				delete(remaining, e.TopicPartition.Partition)
+			}
+		case ckafka.Error:
+			if e.IsFatal() {
+				return nil, e
+			}
+		case nil:
+			// The last offsets of a partition may hold no message, e.g.
+			// transaction markers, so the position tells when it is done.
+			positions, err := consumer.Position(assigned)
+			if err != nil {
+				return nil, err
+			}
+			for _, tp := range positions {
+				if int64(tp.Offset) >= end[tp.Partition] {
+					delete(remaining, tp.Partition)
+				}
+			}
+		}
+	}
+	return elements, nil
+}
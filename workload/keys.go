@@ -0,0 +1,274 @@
+package workload
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// absent is the value of a key that has no acknowledged write.
+const absent = -1
+
+// keyState is what the workload knows about the value of one key. Values
+// only grow per key, so everything below acked is settled.
+type keyState struct {
+	// acked is the highest acknowledged value, or absent.
+	acked int
+	// failed holds the values above acked whose write definitely did not
+	// take effect.
+	failed map[int]bool
+	// indeterminate holds the values above acked whose write timed out or
+	// lost its connection and may or may not have taken effect.
+	indeterminate map[int]bool
+	// recovered holds the indeterminate values a read showed were applied.
+	recovered map[int]bool
+	// deletes holds the values of the deletes above acked that did not
+	// fail, and the highest one at or below it, after any of which the key
+	// may be absent.
+	deletes map[int]bool
+	// next is the value of the next write issued to the key. Values count
+	// the writes of a key, so the first one, with value 0, inserts it.
+	next int
+}
+
+// KeyValue is one value of one key.
+type KeyValue struct {
+	Key   int `json:"key"`
+	Value int `json:"value"`
+}
+
+// keys tracks the acknowledged, failed and indeterminate writes of every key
+// the workload touched, so a read can be told apart as intact, lost or
+// unexpected.
+type keys struct {
+	mu    sync.Mutex
+	state map[int]*keyState
+	// recovered holds the indeterminate writes a read showed were applied.
+	recovered []KeyValue
+	// pending holds every issued write that has not completed yet, by
+	// sequence, and next the sequence issued next.
+	pending map[int]PendingWrite
+	next    int
+}
+
+func newKeys() *keys {
+	return &keys{state: map[int]*keyState{}, pending: map[int]PendingWrite{}}
+}
+
+// issue records that the write of sequence to key, or its delete, is handed
+// to the pool at ts, and returns the value it writes. A delete takes a value
+// too, so the writes and deletes of a key are ordered by value.
+func (k *keys) issue(sequence, key int, del bool, ts time.Time) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s := k.get(key)
+	value := s.next
+	s.next++
+	if del {
+		s.deletes[value] = true
+	}
+	k.pending[sequence] = PendingWrite{Sequence: sequence, Key: key, Value: value, Delete: del, Invoke: ts}
+	k.next = sequence + 1
+	return value
+}
+
+// drop undoes issue for a write the pool rejected.
+func (k *keys) drop(sequence int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	pending := k.pending[sequence]
+	s := k.get(pending.Key)
+	s.next--
+	delete(s.deletes, pending.Value)
+	delete(k.pending, sequence)
+	k.next = sequence
+}
+
+func (k *keys) get(key int) *keyState {
+	s, ok := k.state[key]
+	if !ok {
+		s = &keyState{
+			acked:         absent,
+			failed:        map[int]bool{},
+			indeterminate: map[int]bool{},
+			recovered:     map[int]bool{},
+			deletes:       map[int]bool{},
+		}
+		k.state[key] = s
+	}
+	return s
+}
+
+// write records the outcome of the write of sequence, which wrote value to
+// key.
+func (k *keys) write(sequence, key, value int, outcome history.Outcome) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.pending, sequence)
+	s := k.get(key)
+	switch outcome {
+	case history.OK:
+		// Concurrent writes to a key may be acknowledged out of order.
+		if value <= s.acked {
+			return
+		}
+		s.acked = value
+		for v := range s.failed {
+			if v <= value {
+				delete(s.failed, v)
+			}
+		}
+		for v := range s.indeterminate {
+			if v <= value {
+				delete(s.indeterminate, v)
+			}
+		}
+		for v := range s.recovered {
+			if v <= value {
+				delete(s.recovered, v)
+			}
+		}
+		// The writes of a key do not overlap, so the deletes up to value
+		// completed and only the highest of them still matters.
+		last := absent
+		for v := range s.deletes {
+			if v <= value {
+				last = max(last, v)
+			}
+		}
+		for v := range s.deletes {
+			if v < last {
+				delete(s.deletes, v)
+			}
+		}
+	case history.Fail:
+		if s.deletes[value] {
+			delete(s.deletes, value)
+			return
+		}
+		if value > s.acked {
+			s.failed[value] = true
+		}
+	case history.Info:
+		if value > s.acked {
+			s.indeterminate[value] = true
+		}
+	}
+}
+
+// touched returns every key the workload issued a write to, in order.
+func (k *keys) touched() []int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := make([]int, 0, len(k.state))
+	for key := range k.state {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// acked returns the highest acknowledged value of key, or absent.
+func (k *keys) acked(key int) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.get(key).acked
+}
+
+// read classifies the value read from key by a read started when acked was
+// the highest acknowledged value. It returns AnomalyLostWrite if the
+// acknowledged value is provably missing, AnomalyUnexpected if the value was
+// written by a write that failed, and "" otherwise. A read of an indeterminate
+// write marks that write as recovered.
+func (k *keys) read(key int, value *int, acked int) string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s := k.get(key)
+	if value == nil {
+		if acked == absent || s.deletedSince(acked) {
+			return ""
+		}
+		return AnomalyLostWrite
+	}
+	if *value < acked {
+		return AnomalyLostWrite
+	}
+	if s.failed[*value] {
+		// Reported once, later reads of the value are no news.
+		delete(s.failed, *value)
+		return AnomalyUnexpected
+	}
+	k.recover(s, key, *value)
+	return ""
+}
+
+// deletedSince reports whether a delete at or above value did not fail.
+func (s *keyState) deletedSince(value int) bool {
+	for v := range s.deletes {
+		if v >= value {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *keys) recover(s *keyState, key, value int) {
+	if s.indeterminate[value] {
+		delete(s.indeterminate, value)
+		s.recovered[value] = true
+		k.recovered = append(k.recovered, KeyValue{Key: key, Value: value})
+	}
+}
+
+// Durability classifies the value of a key read once writing stopped.
+type Durability string
+
+const (
+	// Intact keys hold the highest acknowledged value, or an indeterminate
+	// value above it.
+	Intact Durability = "intact"
+	// Lost keys have no value although a write was acknowledged.
+	Lost Durability = "lost"
+	// Stale keys hold an older value than the highest acknowledged one.
+	Stale Durability = "stale"
+	// Unexpected keys hold a value no write of which succeeded or may have
+	// succeeded.
+	Unexpected Durability = "unexpected"
+)
+
+// classify compares the final value of key with every write the workload
+// issued to it. It must only be called once all writes have completed.
+func (k *keys) classify(key int, value *int) Durability {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	s := k.get(key)
+	switch {
+	case value == nil && (s.acked == absent || s.deletes[s.acked]):
+		return Intact
+	case value == nil:
+		for v := range s.deletes {
+			if v > s.acked && s.indeterminate[v] {
+				return Intact
+			}
+		}
+		return Lost
+	case *value < s.acked:
+		return Stale
+	case *value == s.acked:
+		return Intact
+	case s.indeterminate[*value] || s.recovered[*value]:
+		k.recover(s, key, *value)
+		return Intact
+	default:
+		return Unexpected
+	}
+}
+
+// recoveredWrites returns the indeterminate writes reads showed were applied.
+func (k *keys) recoveredWrites() []KeyValue {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return append([]KeyValue(nil), k.recovered...)
+}
@@ -0,0 +1,119 @@
+package workload
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// OpType identifies the kind of operation a latency was recorded for.
+type OpType string
+
+const (
+	OpInsert OpType = "insert"
+	OpUpdate OpType = "update"
+	OpUpsert OpType = "upsert"
+	OpDelete OpType = "delete"
+	OpRead   OpType = "read"
+	OpScan   OpType = "scan"
+	// OpReadModifyWrite reads a key and then writes it, like the
+	// read-modify-write of YCSB.
+	OpReadModifyWrite OpType = "read-modify-write"
+	// OpTransfer moves money between two accounts of the bank workload,
+	// and OpBalances reads the balances of all of them.
+	OpTransfer OpType = "transfer"
+	OpBalances OpType = "balances"
+	// OpTxn is a transaction of the list-append workload.
+	OpTxn OpType = "txn"
+	// OpAdd adds an element to the set of the set workload, and OpReadSet
+	// reads all of them.
+	OpAdd     OpType = "add"
+	OpReadSet OpType = "read-set"
+	// OpTrial is a trial of the isolation suite, which neither reads nor
+	// writes keys and counts towards no availability.
+	OpTrial OpType = "trial"
+	// OpCheck is a read of the checker, not part of the load.
+	OpCheck OpType = "check"
+)
+
+// mutates reports whether op changes the key it is issued to.
+func (op OpType) mutates() bool {
+	switch op {
+	case OpRead, OpScan, OpCheck, OpBalances, OpReadSet, OpTrial:
+		return false
+	}
+	return true
+}
+
+const (
+	// Latencies are recorded in microseconds between 1us and a minute with
+	// three significant digits.
+	minLatency  = 1
+	maxLatency  = int64(time.Minute / time.Microsecond)
+	latencySigs = 3
+)
+
+// latencies aggregates operation latencies into one HDR histogram per
+// operation type for the current report interval.
+type latencies struct {
+	mu    sync.Mutex
+	hists map[OpType]*hdrhistogram.Histogram
+}
+
+func newLatencies() *latencies {
+	return &latencies{hists: map[OpType]*hdrhistogram.Histogram{}}
+}
+
+func (l *latencies) record(op OpType, d time.Duration) {
+	us := d.Microseconds()
+	if us < minLatency {
+		us = minLatency
+	}
+	if us > maxLatency {
+		us = maxLatency
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hists[op]
+	if !ok {
+		h = hdrhistogram.New(minLatency, maxLatency, latencySigs)
+		l.hists[op] = h
+	}
+	h.RecordValue(us)
+}
+
+// snapshot returns the histograms of the current interval and starts a new
+// one.
+func (l *latencies) snapshot() map[OpType]*hdrhistogram.Histogram {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hists := l.hists
+	l.hists = map[OpType]*hdrhistogram.Histogram{}
+	return hists
+}
+
+// latencyEvents turns the histograms of the interval ending at ts into one
+// event per operation type.
+func latencyEvents(ts time.Time, hists map[OpType]*hdrhistogram.Histogram) []LatencyEvent {
+	events := make([]LatencyEvent, 0, len(hists))
+	for _, op := range sortedKeys(hists) {
+		h := hists[op]
+		events = append(events, LatencyEvent{
+			Time:   ts,
+			Op:     op,
+			Count:  h.TotalCount(),
+			P50Ms:  usToMs(h.ValueAtQuantile(50)),
+			P90Ms:  usToMs(h.ValueAtQuantile(90)),
+			P99Ms:  usToMs(h.ValueAtQuantile(99)),
+			P999Ms: usToMs(h.ValueAtQuantile(99.9)),
+			MaxMs:  usToMs(h.Max()),
+		})
+	}
+	return events
+}
+
+func usToMs(us int64) float64 {
+	return float64(us) / 1000
+}
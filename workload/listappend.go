@@ -0,0 +1,247 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload/checker"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// ListAppendDriver is implemented by backends with transactions over several
+// keys that can run the list-append workload.
+type ListAppendDriver interface {
+	Driver
+	// SetupLists (re)creates the empty lists.
+	SetupLists(ctx context.Context) error
+	// Txn runs mops in one transaction, in order: appends add their value
+	// to the end of the list under their key, and reads fill in its List,
+	// empty if the key holds no list yet.
+	Txn(ctx context.Context, mops []history.Mop) error
+}
+
+// ListAppendConfig configures the list-append workload on top of the Config
+// shared with the register workload.
+type ListAppendConfig struct {
+	// Keys is the number of keys the transactions pick from at a time. A
+	// key is replaced by a fresh one once it got about MaxWritesPerKey
+	// appends, so the lists stay short.
+	Keys            int
+	MaxWritesPerKey int
+	// MaxTxnLength is the most micro-operations a transaction runs.
+	MaxTxnLength int
+}
+
+func DefaultListAppendConfig() ListAppendConfig {
+	return ListAppendConfig{
+		Keys:            10,
+		MaxWritesPerKey: 32,
+		MaxTxnLength:    4,
+	}
+}
+
+func (c ListAppendConfig) Validate() error {
+	if c.Keys <= 0 {
+		return fmt.Errorf("keys must be positive, got %d", c.Keys)
+	}
+	if c.MaxWritesPerKey <= 0 {
+		return fmt.Errorf("max writes per key must be positive, got %d", c.MaxWritesPerKey)
+	}
+	if c.MaxTxnLength <= 0 {
+		return fmt.Errorf("max transaction length must be positive, got %d", c.MaxTxnLength)
+	}
+	return nil
+}
+
+// ListAppendReport summarizes the check of the list-append transactions.
+type ListAppendReport struct {
+	Txns int `json:"txns"`
+	Keys int `json:"keys"`
+	// Recovered counts the transactions with an unknown outcome whose
+	// appends were read.
+	Recovered int              `json:"recovered"`
+	Valid     checker.Validity `json:"valid"`
+}
+
+// ListAppend runs transactions that append unique values to the lists under
+// a few keys and read whole lists, at the scheduled rate. Once they
+// completed, the list-append checker infers the dependencies between them
+// from what they read and reports every cycle, an anomaly of the isolation
+// the transactions got, as an anomaly of its kind.
+type ListAppend struct {
+	w      *Workload
+	driver ListAppendDriver
+	cfg    ListAppendConfig
+
+	mu  sync.Mutex
+	ops []history.Op
+}
+
+// NewListAppend returns a list-append workload. Of cfg, it uses the settings
+// of the load, its reporting and thresholds, the history and the instance;
+// the settings of the keys and their checks do not apply.
+func NewListAppend(driver ListAppendDriver, cfg Config, lists ListAppendConfig) *ListAppend {
+	return &ListAppend{
+		w:      New(driver, cfg),
+		driver: driver,
+		cfg:    lists,
+	}
+}
+
+// key returns the key of the index-th list. The instances use lists of
+// their own.
+func (s *ListAppend) key(index int) int {
+	return index*s.w.cfg.Instances + s.w.cfg.Instance
+}
+
+// generation returns how many sequences a key is active for: a transaction
+// runs (MaxTxnLength+1)/2 micro-operations on average, half of them appends
+// spread over Keys keys, so a key gets about MaxWritesPerKey appends.
+func (s *ListAppend) generation() int {
+	return max(1, 4*s.cfg.MaxWritesPerKey*s.cfg.Keys/(s.cfg.MaxTxnLength+1))
+}
+
+// active returns the key of slot at sequence. Every slot moves on to a fresh
+// key once a generation, the slots staggered so they do not all move at once.
+func (s *ListAppend) active(slot, sequence int) int {
+	generation := s.generation()
+	round := (sequence + slot*generation/s.cfg.Keys) / generation
+	return s.key(round*s.cfg.Keys + slot)
+}
+
+// txn returns the micro-operations of the transaction of sequence, picked
+// from the seed and the sequence alone: half of them reads, half appends of
+// values unique across the instances.
+func (s *ListAppend) txn(sequence int) []history.Mop {
+	h := random(s.w.cfg.Seed, sequence)
+	mops := make([]history.Mop, 1+pick(h, s.cfg.MaxTxnLength))
+	for i := range mops {
+		h = mix(h)
+		key := s.active(pick(h, s.cfg.Keys), sequence)
+		if h = mix(h); pick(h, 2) == 0 {
+			mops[i] = history.Mop{F: history.Read, Key: key}
+			continue
+		}
+		value := (sequence*s.w.cfg.Instances+s.w.cfg.Instance)*s.cfg.MaxTxnLength + i + 1
+		mops[i] = history.Mop{F: history.Append, Key: key, Value: history.IntPtr(value)}
+	}
+	return mops
+}
+
+func (s *ListAppend) Run(ctx context.Context) error {
+	w := s.w
+	if err := w.cfg.Validate(); err != nil {
+		return err
+	}
+	if err := s.cfg.Validate(); err != nil {
+		return err
+	}
+	// Only the first instance sets up the lists for all of them.
+	if w.cfg.Instance == 0 {
+		if err := s.driver.SetupLists(ctx); err != nil {
+			return fmt.Errorf("setup: %w", err)
+		}
+	}
+
+	start, dropped, err := w.drive(ctx, loop{do: s.do})
+	if err != nil {
+		return err
+	}
+	report, err := s.check()
+	if err != nil {
+		return err
+	}
+	return w.finish(w.tally.summary(Summary{
+		Backend:    w.cfg.Backend,
+		Client:     w.cfg.Client,
+		Start:      start,
+		End:        time.Now(),
+		Dropped:    dropped,
+		ListAppend: report,
+	}))
+}
+
+// do runs the transaction of o and records it.
+func (s *ListAppend) do(ctx context.Context, worker int, o op) Result {
+	w := s.w
+	mops := s.txn(o.sequence)
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	err := s.driver.Txn(ctx, mops)
+	end := time.Now()
+	outcome := classify(s.driver, err)
+	if outcome != history.OK {
+		// What a transaction that did not commit read does not count.
+		for i := range mops {
+			mops[i].List = nil
+		}
+	}
+	w.measure(OpTxn, mops[0].Key, nil, start, end, outcome, err)
+
+	txn := history.Op{
+		F:        history.Txn,
+		Key:      mops[0].Key,
+		Outcome:  outcome,
+		Invoke:   start,
+		Complete: end,
+		Mops:     mops,
+	}
+	if err != nil {
+		txn.Error = err.Error()
+	}
+	txn = w.recordOp(worker, txn)
+	s.mu.Lock()
+	txn.Index = len(s.ops)
+	s.ops = append(s.ops, txn)
+	s.mu.Unlock()
+	return Result{err: err, ts: o.ts}
+}
+
+// check runs the list-append checker over the transactions, read back from
+// the history if one was recorded so the reported indices match it, and
+// reports its anomalies.
+func (s *ListAppend) check() (*ListAppendReport, error) {
+	w := s.w
+	s.mu.Lock()
+	ops := s.ops
+	s.mu.Unlock()
+	if w.cfg.History != nil {
+		var err error
+		if ops, err = w.cfg.History.Ops(); err != nil {
+			return nil, fmt.Errorf("loading history: %w", err)
+		}
+	} else {
+		sort.SliceStable(ops, func(i, j int) bool { return ops[i].Invoke.Before(ops[j].Invoke) })
+	}
+
+	result := checker.ListAppend(ops)
+	for _, a := range result.Anomalies {
+		inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+		w.tally.anomaly(a.Kind)
+		w.reporter.Anomaly(AnomalyEvent{
+			Time:    time.Now(),
+			Kind:    a.Kind,
+			Key:     a.Key,
+			Message: a.Message,
+			Ops:     a.Ops,
+		})
+	}
+
+	report := &ListAppendReport{Recovered: len(result.Recovered), Valid: result.Valid}
+	keys := map[int]bool{}
+	for _, op := range ops {
+		if op.F != history.Txn {
+			continue
+		}
+		report.Txns++
+		for _, m := range op.Mops {
+			keys[m.Key] = true
+		}
+	}
+	report.Keys = len(keys)
+	return report, nil
+}
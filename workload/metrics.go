@@ -0,0 +1,73 @@
+package workload
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	opsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "workload_operations_total",
+		Help: "Operations issued to the backend by operation type and outcome.",
+	}, []string{"backend", "op", "outcome"})
+
+	opDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "workload_operation_duration_seconds",
+		Help:    "Latency of operations issued to the backend.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+	}, []string{"backend", "op"})
+
+	inFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "workload_in_flight_operations",
+		Help: "Writes currently outstanding at the backend.",
+	}, []string{"backend"})
+
+	droppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "workload_dropped_operations_total",
+		Help: "Scheduled writes dropped because the in-flight limit and queue were full.",
+	}, []string{"backend"})
+
+	inconsistenciesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "workload_inconsistencies_total",
+		Help: "Reads that missed an acknowledged write or returned the value of a failed write.",
+	}, []string{"backend"})
+
+	checkerKeys = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "workload_checker_keys",
+		Help: "Keys with an acknowledged write the checker verifies.",
+	}, []string{"backend"})
+
+	checkerPassesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "workload_checker_passes_total",
+		Help: "Completed checker passes over all acknowledged keys.",
+	}, []string{"backend"})
+
+	// ReceivedTotal counts messages consumed by the receivers of message
+	// queue backends.
+	ReceivedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "workload_received_messages_total",
+		Help: "Messages consumed from the backend.",
+	}, []string{"backend"})
+)
+
+// ServeMetrics exposes the Prometheus metrics on addr at /metrics in the
+// background. An empty addr disables the endpoint.
+func ServeMetrics(addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		err := http.ListenAndServe(addr, mux)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Error serving metrics: %s\n", err)
+		}
+	}()
+}
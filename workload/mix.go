@@ -0,0 +1,104 @@
+package workload
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mix weighs the operation types the load is made of. Like a Distribution,
+// the type of every operation is a pure function of its sequence.
+type Mix struct {
+	ops     []OpType
+	weights []float64
+	total   float64
+	seed    int64
+}
+
+// mixOps are the operation types a Mix can contain.
+var mixOps = []OpType{OpRead, OpInsert, OpUpdate, OpUpsert, OpDelete, OpScan, OpReadModifyWrite}
+
+// ParseMix parses a comma-separated list of operation types with their
+// weights, e.g. "read=50,update=40,delete=5,scan=5". The weights need not add
+// up to 100. An empty spec returns nil: every operation is a write that
+// inserts the first value of a key and updates the later ones.
+func ParseMix(spec string, seed int64) (*Mix, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	m := &Mix{seed: seed}
+	for _, part := range strings.Split(spec, ",") {
+		name, weight, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("mix %q: expected op=weight, got %q", spec, part)
+		}
+		op := OpType(strings.TrimSpace(name))
+		if !m.valid(op) {
+			return nil, fmt.Errorf("mix %q: unknown operation %q", spec, op)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("mix %q: invalid weight %q of %s", spec, weight, op)
+		}
+		if w == 0 {
+			continue
+		}
+		m.ops = append(m.ops, op)
+		m.weights = append(m.weights, w)
+		m.total += w
+	}
+	if m.total == 0 {
+		return nil, fmt.Errorf("mix %q: no operation has a weight", spec)
+	}
+	return m, nil
+}
+
+func (m *Mix) valid(op OpType) bool {
+	for _, known := range mixOps {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Op returns the operation type of sequence.
+func (m *Mix) Op(sequence int) OpType {
+	// The key distributions use the bits of random directly, so the
+	// operation type is picked from independent ones.
+	u := uniform(mix(random(m.seed, sequence)^0x5bd1e995)) * m.total
+	for i, w := range m.weights {
+		if u < w {
+			return m.ops[i]
+		}
+		u -= w
+	}
+	return m.ops[len(m.ops)-1]
+}
+
+// Ops returns the operation types with a weight.
+func (m *Mix) Ops() []OpType {
+	return append([]OpType(nil), m.ops...)
+}
+
+// supports returns an error naming the first operation type of m the driver
+// cannot run.
+func (m *Mix) supports(d Driver) error {
+	for _, op := range m.ops {
+		ok := true
+		switch op {
+		case OpInsert:
+			_, ok = d.(Inserter)
+		case OpUpdate:
+			_, ok = d.(Updater)
+		case OpDelete:
+			_, ok = d.(Deleter)
+		case OpScan:
+			_, ok = d.(Scanner)
+		}
+		if !ok {
+			return fmt.Errorf("the driver does not support %s operations", op)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+var _ workload.ListAppendDriver = &Driver{}
+
+// lists is the collection of the list-append workload, one document per key
+// with the list stored as an array in `value`.
+func (d *Driver) lists() *mongo.Collection {
+	return d.client.Database("mongodb").Collection("lists")
+}
+
+// SetupLists recreates the lists collection. Transactions cannot create it
+// on a first append on every server version.
+func (d *Driver) SetupLists(ctx context.Context) error {
+	if err := d.lists().Drop(ctx); err != nil {
+		return err
+	}
+	return d.client.Database("mongodb").CreateCollection(ctx, "lists")
+}
+
+// Txn runs mops in a multi-document transaction with snapshot reads and
+// majority writes, which needs a replica set. The driver retries the
+// transaction on transient errors until ctx is done.
+func (d *Driver) Txn(ctx context.Context, mops []history.Mop) error {
+	session, err := d.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.Majority())
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		for i, m := range mops {
+			filter := bson.D{{Key: "_id", Value: int32(m.Key)}}
+			if m.F == history.Append {
+				_, err := d.lists().UpdateOne(sc, filter,
+					bson.D{{Key: "$push", Value: bson.D{{Key: "value", Value: int32(*m.Value)}}}},
+					options.Update().SetUpsert(true))
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var doc struct {
+				Value []int `bson:"value"`
+			}
+			err := d.lists().FindOne(sc, filter).Decode(&doc)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, fmt.Errorf("reading list: %w", err)
+			}
+			mops[i].List = append([]int{}, doc.Value...)
+		}
+		return nil, nil
+	}, opts)
+	return err
+}
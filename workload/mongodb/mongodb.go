@@ -0,0 +1,208 @@
+// Package mongodb implements the workload driver for MongoDB.
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+type Config struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	// Record switches the driver from the test collection to the shared
+	// record schema.
+	Record *workload.Record
+}
+
+func (c Config) DSN() string {
+	return fmt.Sprintf("mongodb://%s:%s@%s:%s",
+		c.User, c.Password, c.Host, c.Port)
+}
+
+// Driver writes the workload into the mongodb.test collection, one document
+// per key with the value stored in `sequence`, or into the collection of the
+// Record schema.
+type Driver struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	record     *workload.Record
+}
+
+var (
+	_ workload.Driver          = &Driver{}
+	_ workload.Inserter        = &Driver{}
+	_ workload.Updater         = &Driver{}
+	_ workload.Deleter         = &Driver{}
+	_ workload.Scanner         = &Driver{}
+	_ workload.ErrorClassifier = &Driver{}
+	_ workload.Pinger          = &Driver{}
+)
+
+func NewDriver(ctx context.Context, cfg Config) (*Driver, error) {
+	client, err := mongo.Connect(ctx,
+		options.Client().ApplyURI(cfg.DSN()).SetRetryWrites(false))
+	if err != nil {
+		return nil, err
+	}
+	name := "test"
+	if cfg.Record != nil {
+		name = workload.RecordTable
+	}
+	return &Driver{
+		client:     client,
+		collection: client.Database("mongodb").Collection(name),
+		record:     cfg.Record,
+	}, nil
+}
+
+func (d *Driver) Setup(ctx context.Context) error {
+	return nil
+}
+
+// id returns the _id of the document of key.
+func (d *Driver) id(key int) any {
+	if d.record != nil {
+		return d.record.Key(key)
+	}
+	return int32(key)
+}
+
+// valueField is the field the value is stored in.
+func (d *Driver) valueField() string {
+	if d.record != nil {
+		return "value"
+	}
+	return "sequence"
+}
+
+func (d *Driver) document(key, value int) bson.D {
+	doc := bson.D{
+		{Key: "_id", Value: d.id(key)},
+		{Key: d.valueField(), Value: int32(value)},
+	}
+	if d.record != nil {
+		for i := 0; i < d.record.Fields; i++ {
+			doc = append(doc, bson.E{Key: d.record.FieldName(i), Value: d.record.Field(key, value, i)})
+		}
+	}
+	return doc
+}
+
+// decode returns the key and value of doc.
+func (d *Driver) decode(doc bson.M) (int, int, error) {
+	value, ok := doc[d.valueField()].(int32)
+	if !ok {
+		return 0, 0, fmt.Errorf("decoding document: %s is %T", d.valueField(), doc[d.valueField()])
+	}
+	switch id := doc["_id"].(type) {
+	case int32:
+		return int(id), int(value), nil
+	case string:
+		if d.record != nil {
+			key, err := d.record.ParseKey(id)
+			return key, int(value), err
+		}
+	}
+	return 0, 0, fmt.Errorf("decoding document: _id is %T", doc["_id"])
+}
+
+func (d *Driver) Write(ctx context.Context, key, value int) error {
+	_, err := d.collection.ReplaceOne(ctx,
+		bson.D{{Key: "_id", Value: d.id(key)}}, d.document(key, value),
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+func (d *Driver) Insert(ctx context.Context, key, value int) error {
+	_, err := d.collection.InsertOne(ctx, d.document(key, value))
+	if mongo.IsDuplicateKeyError(err) {
+		return workload.ErrExists
+	}
+	return err
+}
+
+func (d *Driver) Update(ctx context.Context, key, value int) error {
+	result, err := d.collection.ReplaceOne(ctx,
+		bson.D{{Key: "_id", Value: d.id(key)}}, d.document(key, value))
+	if err == nil && result.MatchedCount == 0 {
+		return workload.ErrNotFound
+	}
+	return err
+}
+
+func (d *Driver) Delete(ctx context.Context, key int) error {
+	_, err := d.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: d.id(key)}})
+	return err
+}
+
+func (d *Driver) Scan(ctx context.Context, from, to int) ([]workload.KeyValue, error) {
+	cursor, err := d.collection.Find(ctx,
+		bson.D{{Key: "_id", Value: bson.D{
+			{Key: "$gte", Value: d.id(from)},
+			{Key: "$lt", Value: d.id(to)},
+		}}},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decoding documents: %w", err)
+	}
+	kvs := make([]workload.KeyValue, 0, len(docs))
+	for _, doc := range docs {
+		key, value, err := d.decode(doc)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, workload.KeyValue{Key: key, Value: value})
+	}
+	return kvs, nil
+}
+
+func (d *Driver) Read(ctx context.Context, key int) (int, error) {
+	result := d.collection.FindOne(ctx, bson.D{
+		bson.E{Key: "_id", Value: d.id(key)},
+	})
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return 0, workload.ErrNotFound
+	}
+	if result.Err() != nil {
+		return 0, result.Err()
+	}
+
+	var doc bson.M
+	if err := result.Decode(&doc); err != nil {
+		return 0, fmt.Errorf("decoding document: %w", err)
+	}
+	_, value, err := d.decode(doc)
+	return value, err
+}
+
+func (d *Driver) Ping(ctx context.Context) error {
+	return d.client.Ping(ctx, readpref.Primary())
+}
+
+// Indeterminate reports whether a write may have been applied despite err,
+// including a transaction whose commit the server could not confirm.
+func (d *Driver) Indeterminate(err error) bool {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorLabel("UnknownTransactionCommitResult") {
+		return true
+	}
+	return mongo.IsTimeout(err) || mongo.IsNetworkError(err)
+}
+
+func (d *Driver) Close() error {
+	return d.client.Disconnect(context.Background())
+}
@@ -0,0 +1,53 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+var _ workload.SetDriver = &Driver{}
+
+// elements is the collection of the set workload, one document per element
+// with the set it belongs to in `set`.
+func (d *Driver) elements() *mongo.Collection {
+	return d.client.Database("mongodb").Collection("set")
+}
+
+// SetupSet deletes the documents of set.
+func (d *Driver) SetupSet(ctx context.Context, set int) error {
+	_, err := d.elements().DeleteMany(ctx, bson.D{{Key: "set", Value: int32(set)}})
+	return err
+}
+
+// Add inserts element as a document. Elements are unique across the sets,
+// so the element is the _id.
+func (d *Driver) Add(ctx context.Context, set, element int) error {
+	_, err := d.elements().InsertOne(ctx, bson.D{
+		{Key: "_id", Value: int64(element)},
+		{Key: "set", Value: int32(set)},
+	})
+	return err
+}
+
+func (d *Driver) ReadSet(ctx context.Context, set int) ([]int, error) {
+	cursor, err := d.elements().Find(ctx, bson.D{{Key: "set", Value: int32(set)}})
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		ID int64 `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decoding documents: %w", err)
+	}
+	elements := make([]int, len(docs))
+	for i, doc := range docs {
+		elements[i] = int(doc.ID)
+	}
+	return elements, nil
+}
@@ -0,0 +1,64 @@
+package workload
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// idlePoll is how often a zero-rate schedule is re-evaluated.
+	idlePoll = 100 * time.Millisecond
+	// maxLag bounds how many missed operations are issued back to back after
+	// the pacer fell behind, e.g. because the process was descheduled.
+	maxLag = time.Second
+)
+
+// pacer spaces out operations following a Schedule.
+type pacer struct {
+	schedule Schedule
+	start    time.Time
+	next     time.Time
+}
+
+func newPacer(schedule Schedule) *pacer {
+	now := time.Now()
+	return &pacer{schedule: schedule, start: now, next: now}
+}
+
+// wait blocks until the next operation is due and returns the time it was
+// due at.
+func (p *pacer) wait(ctx context.Context) (time.Time, error) {
+	for {
+		rate := p.schedule.Rate(p.next.Sub(p.start))
+		if rate > 0 {
+			p.next = p.next.Add(time.Duration(float64(time.Second) / rate))
+		} else {
+			p.next = p.next.Add(idlePoll)
+		}
+		if now := time.Now(); now.Sub(p.next) > maxLag {
+			p.next = now
+		}
+
+		timer := time.NewTimer(time.Until(p.next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Time{}, ctx.Err()
+		case <-timer.C:
+		}
+		if rate > 0 {
+			return p.next, nil
+		}
+	}
+}
+
+// intended is the average scheduled rate between from and to.
+func (p *pacer) intended(from, to time.Time) float64 {
+	const samples = 10
+	sum := 0.0
+	step := to.Sub(from) / samples
+	for i := 0; i < samples; i++ {
+		sum += p.schedule.Rate(from.Add(step*time.Duration(i) + step/2).Sub(p.start))
+	}
+	return sum / samples
+}
@@ -0,0 +1,99 @@
+package workload
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An op is one scheduled write handed to the pool.
+type op struct {
+	ts       time.Time
+	sequence int
+	op       OpType
+	// key is the key the operation goes to, and value what a write writes.
+	key, value int
+	// after is closed once the previous write to key completed, and done
+	// once this one did, so the writes of a key never overlap and the key
+	// ends up with the value written last.
+	after, done chan struct{}
+}
+
+// pool runs operations on a fixed number of workers so a hanging backend
+// cannot make goroutines pile up. Operations that find every worker busy wait
+// in a bounded queue; once the queue is full they are dropped.
+type pool struct {
+	ops     chan op
+	workers int
+	wg      sync.WaitGroup
+
+	busy atomic.Int64
+	// accepted, queued and dropped count operations since the last snapshot.
+	accepted atomic.Int64
+	queued   atomic.Int64
+	dropped  atomic.Int64
+	// droppedTotal counts dropped operations over the whole run.
+	droppedTotal atomic.Int64
+}
+
+// poolStats is a snapshot of the pool, taken once per report interval.
+type poolStats struct {
+	accepted int64
+	queued   int64
+	dropped  int64
+	inFlight int64
+}
+
+// newPool starts workers that call do with their index and the operation.
+func newPool(workers, queueSize int, do func(worker int, o op)) *pool {
+	p := &pool{
+		ops:     make(chan op, queueSize),
+		workers: workers,
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(worker int) {
+			defer p.wg.Done()
+			for o := range p.ops {
+				p.busy.Add(1)
+				do(worker, o)
+				p.busy.Add(-1)
+			}
+		}(i)
+	}
+	return p
+}
+
+// submit hands o to the pool without blocking and reports whether it was
+// accepted.
+func (p *pool) submit(o op) bool {
+	saturated := p.busy.Load() >= int64(p.workers)
+	select {
+	case p.ops <- o:
+		p.accepted.Add(1)
+		if saturated {
+			p.queued.Add(1)
+		}
+		return true
+	default:
+		p.dropped.Add(1)
+		p.droppedTotal.Add(1)
+		return false
+	}
+}
+
+// close waits for every accepted operation to complete.
+func (p *pool) close() {
+	close(p.ops)
+	p.wg.Wait()
+}
+
+// snapshot returns the counters since the previous snapshot and resets them.
+func (p *pool) snapshot() poolStats {
+	return poolStats{
+		accepted: p.accepted.Swap(0),
+		queued:   p.queued.Swap(0),
+		dropped:  p.dropped.Swap(0),
+		inFlight: p.busy.Load() + int64(len(p.ops)),
+	}
+}
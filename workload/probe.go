@@ -0,0 +1,100 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Probe tells whether the backend is healthy again, before the final read
+// verifies every key.
+type Probe interface {
+	Healthy(ctx context.Context, d Driver) error
+}
+
+// Pinger is implemented by drivers that can check their connection to the
+// backend without touching the workload's data.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DriverProbe pings the backend through the driver, or reads a key if the
+// driver cannot ping. A key that was never written still proves the backend
+// answers.
+type DriverProbe struct{}
+
+func (DriverProbe) Healthy(ctx context.Context, d Driver) error {
+	if p, ok := d.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	_, err := d.Read(ctx, 0)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrReadUnsupported) {
+		return nil
+	}
+	return err
+}
+
+// TCPProbe connects to an address, e.g. the service of the backend.
+type TCPProbe string
+
+func (p TCPProbe) Healthy(ctx context.Context, _ Driver) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", string(p))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTPProbe expects a 2xx response from a URL, e.g. a health endpoint of the
+// backend or its operator.
+type HTTPProbe string
+
+func (p HTTPProbe) Healthy(ctx context.Context, _ Driver) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, string(p), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", p, resp.Status)
+	}
+	return nil
+}
+
+// NoProbe considers the backend healthy right away.
+type NoProbe struct{}
+
+func (NoProbe) Healthy(context.Context, Driver) error { return nil }
+
+// ParseProbe parses a probe spec:
+//
+//	driver                   ping or read through the driver (default)
+//	tcp:host:port            connect to the address
+//	http://host:port/path    expect a 2xx response, https:// works too
+//	none                     do not wait
+func ParseProbe(spec string) (Probe, error) {
+	switch {
+	case spec == "driver":
+		return DriverProbe{}, nil
+	case spec == "none":
+		return NoProbe{}, nil
+	case strings.HasPrefix(spec, "tcp:"):
+		addr := strings.TrimPrefix(spec, "tcp:")
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("probe %q: %w", spec, err)
+		}
+		return TCPProbe(addr), nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return HTTPProbe(spec), nil
+	default:
+		return nil, fmt.Errorf("unknown probe %q", spec)
+	}
+}
@@ -0,0 +1,57 @@
+package workload
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile presets the load of one of the core workloads of YCSB.
+type Profile struct {
+	Name        string
+	Description string
+	// Mix, KeyDistribution and ScanLength are set in the Config.
+	Mix             string
+	KeyDistribution string
+	ScanLength      int
+}
+
+// Profiles are the YCSB core workloads A to F. The key space is fixed, so
+// the inserts of D and E, which add records in YCSB, rewrite the newest
+// record with an upsert instead, and E scans a fixed number of records, the
+// mean of the up to 100 of YCSB.
+var Profiles = []Profile{
+	{Name: "a", Description: "update heavy", Mix: "read=50,update=50", KeyDistribution: "zipfian"},
+	{Name: "b", Description: "read mostly", Mix: "read=95,update=5", KeyDistribution: "zipfian"},
+	{Name: "c", Description: "read only", Mix: "read=100", KeyDistribution: "zipfian"},
+	{Name: "d", Description: "read latest", Mix: "read=95,upsert=5", KeyDistribution: "latest"},
+	{Name: "e", Description: "short ranges", Mix: "scan=95,upsert=5", KeyDistribution: "zipfian", ScanLength: 50},
+	{Name: "f", Description: "read-modify-write", Mix: "read=50,read-modify-write=50", KeyDistribution: "zipfian"},
+}
+
+// LookupProfile returns the profile called name, case-insensitively.
+func LookupProfile(name string) (Profile, error) {
+	names := make([]string, len(Profiles))
+	for i, p := range Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		names[i] = p.Name
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// Apply sets the load of p in cfg. Like YCSB, the keys are preloaded before
+// the mix starts and stored in the DefaultRecord schema, unless cfg already
+// has a Record.
+func (p Profile) Apply(cfg *Config) {
+	cfg.Mix = p.Mix
+	cfg.KeyDistribution = p.KeyDistribution
+	if p.ScanLength > 0 {
+		cfg.ScanLength = p.ScanLength
+	}
+	cfg.Preload = true
+	if cfg.Record == nil {
+		record := DefaultRecord
+		cfg.Record = &record
+	}
+}
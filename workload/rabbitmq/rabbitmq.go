@@ -0,0 +1,210 @@
+// Package rabbitmq implements the workload driver for RabbitMQ. Every write is
+// published as one persistent message to a durable queue.
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+const QueueName = "task_queue"
+
+type Config struct {
+	Host     string
+	User     string
+	Password string
+}
+
+func (c Config) URL() string {
+	return fmt.Sprintf("amqp://%s:%s@%s:5672/", c.User, c.Password, c.Host)
+}
+
+// Driver publishes on one channel. Once the broker closed the connection or
+// the channel, e.g. in a restart, the next operation dials again.
+type Driver struct {
+	cfg      Config
+	keySpace int
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	// confirm puts every channel dialed in confirm mode, once the set
+	// workload asked for it.
+	confirm bool
+}
+
+var _ workload.Driver = &Driver{}
+
+// NewDriver connects to RabbitMQ. keySpace is needed to turn a key and value
+// into the number carried by the message, unique to every write of an
+// instance.
+func NewDriver(cfg Config, keySpace int) (*Driver, error) {
+	conn, ch, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{cfg: cfg, conn: conn, ch: ch, keySpace: keySpace}, nil
+}
+
+// channel returns the channel to publish on, dialing again if the broker
+// closed it.
+func (d *Driver) channel() (*amqp.Channel, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.redial(); err != nil {
+		return nil, err
+	}
+	return d.ch, nil
+}
+
+// connection returns the connection, dialing again if the broker closed it.
+func (d *Driver) connection() (*amqp.Connection, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.redial(); err != nil {
+		return nil, err
+	}
+	return d.conn, nil
+}
+
+func (d *Driver) redial() error {
+	if !d.conn.IsClosed() && !d.ch.IsClosed() {
+		return nil
+	}
+	d.ch.Close()
+	d.conn.Close()
+	conn, ch, err := dial(d.cfg)
+	if err != nil {
+		return err
+	}
+	if d.confirm {
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			conn.Close()
+			return fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+	}
+	d.conn, d.ch = conn, ch
+	return nil
+}
+
+func dial(cfg Config) (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := amqp.Dial(cfg.URL())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+	return conn, ch, nil
+}
+
+func declareQueue(ch *amqp.Channel) (amqp.Queue, error) {
+	return ch.QueueDeclare(
+		QueueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		nil,       // arguments
+	)
+}
+
+func (d *Driver) Setup(ctx context.Context) error {
+	ch, err := d.channel()
+	if err != nil {
+		return err
+	}
+	if _, err := declareQueue(ch); err != nil {
+		return fmt.Errorf("failed to declare a queue: %w", err)
+	}
+	return nil
+}
+
+func (d *Driver) Write(ctx context.Context, key, value int) error {
+	body := fmt.Sprint(value*d.keySpace + key%d.keySpace)
+	ch, err := d.channel()
+	if err != nil {
+		return err
+	}
+	return ch.PublishWithContext(ctx,
+		"",        // exchange
+		QueueName, // routing key
+		false,     // mandatory
+		false,     // immediate
+		amqp.Publishing{
+			DeliveryMode: amqp.Persistent,
+			ContentType:  "text/plain",
+			Body:         []byte(body),
+		})
+}
+
+func (d *Driver) Read(ctx context.Context, key int) (int, error) {
+	return 0, workload.ErrReadUnsupported
+}
+
+func (d *Driver) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ch.Close()
+	return d.conn.Close()
+}
+
+// Receive consumes the workload queue and logs every message until ctx is
+// cancelled.
+func Receive(ctx context.Context, cfg Config) error {
+	conn, ch, err := dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer ch.Close()
+
+	q, err := declareQueue(ch)
+	if err != nil {
+		return fmt.Errorf("failed to declare a queue: %w", err)
+	}
+
+	err = ch.Qos(
+		1,     // prefetch count
+		0,     // prefetch size
+		false, // global
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
+	}
+
+	msgs, err := ch.Consume(
+		q.Name, // queue
+		"",     // consumer
+		true,   // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register a consumer: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-msgs:
+			if !ok {
+				return fmt.Errorf("delivery channel closed")
+			}
+			workload.ReceivedTotal.WithLabelValues("rabbitmq").Inc()
+			log.Printf("Received a message: %s", d.Body)
+		}
+	}
+}
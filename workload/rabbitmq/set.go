@@ -0,0 +1,162 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+var (
+	_ workload.SetDriver       = &Driver{}
+	_ workload.ErrorClassifier = &Driver{}
+)
+
+var (
+	// errNacked is returned by Add when the broker rejected the message: it
+	// was not enqueued.
+	errNacked = errors.New("message nacked by the broker")
+	// errUnconfirmed is returned by Add when the channel closed before the
+	// broker confirmed the message, which may have been enqueued.
+	errUnconfirmed = errors.New("channel closed before the message was confirmed")
+)
+
+// setQueue is the name of the durable queue of set.
+func setQueue(set int) string {
+	return fmt.Sprintf("set_queue_%d", set)
+}
+
+// SetupSet declares the queue of set and purges it, and puts the channel in
+// confirm mode, and every channel dialed after it, so every add waits until
+// the broker took the message over.
+func (d *Driver) SetupSet(ctx context.Context, set int) error {
+	d.mu.Lock()
+	d.confirm = true
+	d.mu.Unlock()
+	ch, err := d.channel()
+	if err != nil {
+		return err
+	}
+	q, err := ch.QueueDeclare(setQueue(set), true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare a queue: %w", err)
+	}
+	if _, err := ch.QueuePurge(q.Name, false); err != nil {
+		return fmt.Errorf("failed to purge a queue: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return nil
+}
+
+// Add publishes element as a persistent message to the queue of set and waits
+// for the broker to confirm it. A nack fails the add; a channel that closes
+// before the confirm, or ctx expiring first, leaves it unknown. An add on a
+// channel the broker closed dials again first.
+func (d *Driver) Add(ctx context.Context, set, element int) error {
+	ch, err := d.channel()
+	if err != nil {
+		return err
+	}
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx,
+		"",            // exchange
+		setQueue(set), // routing key
+		false,         // mandatory
+		false,         // immediate
+		amqp.Publishing{
+			DeliveryMode: amqp.Persistent,
+			ContentType:  "text/plain",
+			Body:         []byte(strconv.Itoa(element)),
+		})
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		// Closing the channel nacks every message still waiting for its
+		// confirm, which tells nothing about whether it was enqueued.
+		if ch.IsClosed() {
+			return errUnconfirmed
+		}
+		return errNacked
+	}
+	return nil
+}
+
+// Indeterminate reports whether an add may have been applied despite err. A
+// publish on a channel that was already closed never left the client.
+func (d *Driver) Indeterminate(err error) bool {
+	return errors.Is(err, errUnconfirmed)
+}
+
+// ReadSet consumes the messages the queue of set holds when the read starts,
+// without acknowledging them, on a channel of its own. The read is not
+// destructive: closing the channel requeues every delivery, so the queue is
+// left as it was.
+func (d *Driver) ReadSet(ctx context.Context, set int) ([]int, error) {
+	conn, err := d.connection()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(setQueue(set), true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect a queue: %w", err)
+	}
+	if q.Messages == 0 {
+		return nil, nil
+	}
+	// The deliveries are only requeued once the read is done, so the
+	// broker must not stop at a prefetch count of unacknowledged ones.
+	if err := ch.Qos(0, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set QoS: %w", err)
+	}
+	msgs, err := ch.Consume(
+		q.Name, // queue
+		"",     // consumer
+		false,  // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register a consumer: %w", err)
+	}
+
+	return collect(ctx, msgs, q.Messages)
+}
+
+// collect returns the elements of the first n deliveries of msgs.
+func collect(ctx context.Context, msgs <-chan amqp.Delivery, n int) ([]int, error) {
+	elements := make([]int, 0, n)
+	for len(elements) < n {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil, fmt.Errorf("delivery channel closed after %d of %d messages", len(elements), n)
+			}
+			element, err := strconv.Atoi(string(msg.Body))
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", msg.DeliveryTag, err)
+			}
+			elements = append(elements, element)
+		}
+	}
+	return elements, nil
+}
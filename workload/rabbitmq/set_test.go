@@ -0,0 +1,78 @@
+package rabbitmq
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+// largeSet is more elements than a consumer prefetch commonly allows.
+const largeSet = 1500
+
+func TestCollect(t *testing.T) {
+	msgs := make(chan amqp.Delivery, largeSet+1)
+	for i := 0; i <= largeSet; i++ {
+		msgs <- amqp.Delivery{DeliveryTag: uint64(i + 1), Body: []byte(strconv.Itoa(i))}
+	}
+	elements, err := collect(context.Background(), msgs, largeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != largeSet || elements[0] != 0 || elements[largeSet-1] != largeSet-1 {
+		t.Errorf("got %d elements, want the first %d", len(elements), largeSet)
+	}
+
+	close(msgs)
+	if _, err := collect(context.Background(), msgs, 2); err == nil {
+		t.Error("closed delivery channel accepted")
+	}
+}
+
+// TestReadSet adds more elements to a set than fit in a prefetch window and
+// reads them back twice, on the broker at RABBITMQ_TEST_HOST.
+func TestReadSet(t *testing.T) {
+	host := os.Getenv("RABBITMQ_TEST_HOST")
+	if host == "" {
+		t.Skip("RABBITMQ_TEST_HOST not set")
+	}
+	cfg := Config{
+		Host:     host,
+		User:     workload.GetEnvWithDefault("RABBITMQ_TEST_USER", "guest"),
+		Password: workload.GetEnvWithDefault("RABBITMQ_TEST_PASSWORD", "guest"),
+	}
+	d, err := NewDriver(cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	const set = 1000
+	if err := d.SetupSet(ctx, set); err != nil {
+		t.Fatal(err)
+	}
+	for element := 0; element < largeSet; element++ {
+		if err := d.Add(ctx, set, element); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The read is not destructive, so a second one reads the same set.
+	for attempt := 0; attempt < 2; attempt++ {
+		elements, err := d.ReadSet(ctx, set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Ints(elements)
+		if len(elements) != largeSet || elements[0] != 0 || elements[largeSet-1] != largeSet-1 {
+			t.Fatalf("read %d: got %d elements, want %d", attempt, len(elements), largeSet)
+		}
+	}
+}
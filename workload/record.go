@@ -0,0 +1,65 @@
+package workload
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RecordTable is the table, or collection, of the Record schema.
+const RecordTable = "usertable"
+
+// Record is the schema the YCSB profiles use on every backend, so their
+// results compare across backends: one record per key in RecordTable,
+// identified by Key, holding the value of the workload and Fields text
+// fields of FieldLength bytes each.
+type Record struct {
+	Fields      int
+	FieldLength int
+}
+
+// DefaultRecord is the record of YCSB, ten fields of 100 bytes.
+var DefaultRecord = Record{Fields: 10, FieldLength: 100}
+
+func (r Record) validate() error {
+	if r.Fields < 0 || r.FieldLength <= 0 {
+		return fmt.Errorf("record must have a positive field length and no negative number of fields, got %d fields of %d bytes", r.Fields, r.FieldLength)
+	}
+	return nil
+}
+
+// Key returns the id of the record of key. The ids are zero-padded, so they
+// sort like the keys and a range of keys is a range of ids.
+func (r Record) Key(key int) string {
+	return fmt.Sprintf("user%010d", key)
+}
+
+// ParseKey returns the key of the record id.
+func (r Record) ParseKey(id string) (int, error) {
+	var key int
+	if _, err := fmt.Sscanf(id, "user%d", &key); err != nil {
+		return 0, fmt.Errorf("record id %q: %w", id, err)
+	}
+	return key, nil
+}
+
+// FieldName returns the name of field i.
+func (r Record) FieldName(i int) string {
+	return fmt.Sprintf("field%d", i)
+}
+
+// Field returns field i of the record of key when it holds value. The bytes
+// are random letters derived from all three, so every write rewrites every
+// field.
+func (r Record) Field(key, value, i int) string {
+	var b strings.Builder
+	b.Grow(r.FieldLength)
+	seed := int64(random(int64(key), value*r.Fields+i))
+	for j := 0; b.Len() < r.FieldLength; j++ {
+		h := random(seed, j)
+		for n := 0; n < 8 && b.Len() < r.FieldLength; n++ {
+			b.WriteByte('a' + byte(h%26))
+			h >>= 8
+		}
+	}
+	return b.String()
+}
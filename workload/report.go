@@ -0,0 +1,513 @@
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload/checker"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// OperationEvent is the outcome of a single write or checker read.
+type OperationEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"ts"`
+	Op        OpType    `json:"op"`
+	Key       int       `json:"key"`
+	Value     *int      `json:"value,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+}
+
+// WindowSummary is the success rate over one sliding window.
+type WindowSummary struct {
+	Window      string  `json:"window"`
+	Ops         int     `json:"ops"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// IntervalEvent summarizes the writes scheduled within one bucket.
+type IntervalEvent struct {
+	Type        string          `json:"type"`
+	Time        time.Time       `json:"ts"`
+	Ops         int             `json:"ops"`
+	OK          int             `json:"ok"`
+	Failed      int             `json:"failed"`
+	SuccessRate float64         `json:"success_rate"`
+	Windows     []WindowSummary `json:"windows"`
+}
+
+// RateEvent compares the scheduled and achieved rate over one second.
+type RateEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"ts"`
+	Intended float64   `json:"intended"`
+	Achieved float64   `json:"achieved"`
+	InFlight int64     `json:"in_flight"`
+	Queued   int64     `json:"queued"`
+	Dropped  int64     `json:"dropped"`
+}
+
+// LatencyEvent holds the latency percentiles of one operation type over one
+// second.
+type LatencyEvent struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"ts"`
+	Op     OpType    `json:"op"`
+	Count  int64     `json:"count"`
+	P50Ms  float64   `json:"p50_ms"`
+	P90Ms  float64   `json:"p90_ms"`
+	P99Ms  float64   `json:"p99_ms"`
+	P999Ms float64   `json:"p999_ms"`
+	MaxMs  float64   `json:"max_ms"`
+}
+
+// AnomalyEvent is a detected violation of what the backend promised.
+type AnomalyEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"ts"`
+	// Client is the instance that detected the anomaly, set when the
+	// anomalies of several instances are merged.
+	Client string `json:"client,omitempty"`
+	Kind   string `json:"kind"`
+	Key    int    `json:"key"`
+	// Expected is the highest acknowledged value when the read started, or
+	// -1 for none, and Actual the value read, nil if the key had none.
+	Expected int  `json:"expected"`
+	Actual   *int `json:"actual"`
+	// Message and Ops describe anomalies found in the recorded history,
+	// with the operations that demonstrate them.
+	Message string       `json:"message,omitempty"`
+	Ops     []history.Op `json:"ops,omitempty"`
+}
+
+const (
+	// AnomalyLostWrite is a read that misses an acknowledged write.
+	AnomalyLostWrite = checker.AnomalyLostWrite
+	// AnomalyUnexpected is a read of a value whose write failed.
+	AnomalyUnexpected = checker.AnomalyUnexpected
+)
+
+// OpTotals counts the outcomes of one operation type over the whole run.
+type OpTotals struct {
+	OK     int `json:"ok"`
+	Failed int `json:"failed"`
+	// Info counts operations with an unknown outcome, e.g. timed out writes.
+	Info int `json:"info"`
+	// Rejected counts inserts of keys that exist and updates of keys that
+	// do not. They did not apply, but the backend was available.
+	Rejected int `json:"rejected,omitempty"`
+}
+
+// Summary describes a whole run.
+type Summary struct {
+	Type       string              `json:"type"`
+	Backend    string              `json:"backend"`
+	Client     string              `json:"client,omitempty"`
+	Start      time.Time           `json:"start"`
+	End        time.Time           `json:"end"`
+	Operations map[OpType]OpTotals `json:"operations"`
+	Dropped    int64               `json:"dropped"`
+	// Availability is the fraction of writes that succeeded, and
+	// ReadAvailability the one of the reads and scans of the load.
+	Availability     float64        `json:"availability"`
+	ReadAvailability *float64       `json:"read_availability,omitempty"`
+	Anomalies        map[string]int `json:"anomalies"`
+	// Durability is the outcome of the final read of every key, nil if the
+	// backend cannot be read.
+	Durability *DurabilityReport `json:"durability,omitempty"`
+	// Recovered are the writes with an unknown outcome that a read showed
+	// were applied.
+	Recovered []KeyValue `json:"recovered,omitempty"`
+	// Linearizable is the verdict of the linearizability checker, if it ran.
+	Linearizable checker.Validity `json:"linearizable,omitempty"`
+	// Bank is the outcome of the snapshot reads of the bank workload,
+	// Isolation the one of the trials of the isolation suite, ListAppend
+	// the one of the check of the list-append transactions and Set the one
+	// of the final read of the set workload.
+	Bank       *BankReport       `json:"bank,omitempty"`
+	Isolation  *IsolationReport  `json:"isolation,omitempty"`
+	ListAppend *ListAppendReport `json:"list_append,omitempty"`
+	Set        *SetReport        `json:"set,omitempty"`
+	Passed     bool              `json:"passed"`
+	Violations []string          `json:"violations,omitempty"`
+}
+
+// DurabilityReport classifies the keys read once writing stopped.
+type DurabilityReport struct {
+	// Healthy tells whether the probe passed before the recovery timeout,
+	// after waiting WaitMs.
+	Healthy    bool    `json:"healthy"`
+	WaitMs     float64 `json:"wait_ms"`
+	Intact     int     `json:"intact"`
+	Lost       int     `json:"lost"`
+	Stale      int     `json:"stale"`
+	Unexpected int     `json:"unexpected"`
+	// Unreadable keys could not be read.
+	Unreadable int `json:"unreadable"`
+}
+
+// A Reporter receives everything a workload reports.
+type Reporter interface {
+	Operation(OperationEvent)
+	Interval(IntervalEvent)
+	Rate(RateEvent)
+	Latency(LatencyEvent)
+	Anomaly(AnomalyEvent)
+	Summary(Summary)
+}
+
+// TextReporter prints the human readable lines the writers always printed.
+type TextReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewTextReporter(w io.Writer) *TextReporter {
+	return &TextReporter{w: w}
+}
+
+func (r *TextReporter) printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, format, args...)
+}
+
+func (r *TextReporter) Operation(e OperationEvent) {
+	if e.Error == "" {
+		return
+	}
+	if !e.Op.mutates() && e.Op != OpTrial {
+		r.printf("Error reading key %d: %s\n", e.Key, e.Error)
+	} else {
+		r.printf("Error: %s\n", e.Error)
+	}
+}
+
+func (r *TextReporter) Interval(e IntervalEvent) {
+	var line strings.Builder
+	fmt.Fprintf(&line, "TS: [%s], Ops: [%d], Success Rate: [%f]",
+		e.Time.Format(time.RFC3339), e.Ops, e.SuccessRate)
+	for _, window := range e.Windows {
+		fmt.Fprintf(&line, ", Window %s: [%d ops, %f]", window.Window, window.Ops, window.SuccessRate)
+	}
+	r.printf("%s\n", line.String())
+}
+
+func (r *TextReporter) Rate(e RateEvent) {
+	r.printf("TS: [%s], Intended Rate: [%f], Achieved Rate: [%f], In Flight: [%d], Queued: [%d], Dropped: [%d]\n",
+		e.Time.Format(time.RFC3339), e.Intended, e.Achieved, e.InFlight, e.Queued, e.Dropped)
+}
+
+func (r *TextReporter) Latency(e LatencyEvent) {
+	r.printf("TS: [%s], Op: [%s], Count: [%d], p50: [%s], p90: [%s], p99: [%s], p999: [%s], Max: [%s]\n",
+		e.Time.Format(time.RFC3339), e.Op, e.Count,
+		msToDuration(e.P50Ms), msToDuration(e.P90Ms), msToDuration(e.P99Ms),
+		msToDuration(e.P999Ms), msToDuration(e.MaxMs))
+}
+
+func (r *TextReporter) Anomaly(e AnomalyEvent) {
+	prefix := ""
+	if e.Client != "" {
+		prefix = fmt.Sprintf("Client [%s]: ", e.Client)
+	}
+	// Anomalies found by the checker reads are described by the values,
+	// the ones found in the history by the operations that show them.
+	switch {
+	case len(e.Ops) > 0:
+	case e.Kind == AnomalyLostWrite:
+		actual := "no value"
+		if e.Actual != nil {
+			actual = fmt.Sprint(*e.Actual)
+		}
+		r.printf("%sAcknowledged write lost: key %d has %s in the database but %d was acknowledged\n",
+			prefix, e.Key, actual, e.Expected)
+		return
+	case e.Kind == AnomalyUnexpected:
+		r.printf("%sUnexpected value: key %d has %d in the database but no write of it succeeded\n",
+			prefix, e.Key, *e.Actual)
+		return
+	}
+	var text strings.Builder
+	fmt.Fprintf(&text, "%sAnomaly [%s] detected: %s\n", prefix, e.Kind, e.Message)
+	for _, op := range e.Ops {
+		fmt.Fprintf(&text, "  %s\n", op)
+	}
+	r.printf("%s", text.String())
+}
+
+func (r *TextReporter) Summary(s Summary) {
+	for _, kv := range s.Recovered {
+		r.printf("Indeterminate write applied: key %d has %d\n", kv.Key, kv.Value)
+	}
+	var line strings.Builder
+	fmt.Fprintf(&line, "Summary: backend [%s]", s.Backend)
+	if s.Client != "" {
+		fmt.Fprintf(&line, ", client [%s]", s.Client)
+	}
+	fmt.Fprintf(&line, ", duration [%s], availability [%f]",
+		s.End.Sub(s.Start).Round(time.Millisecond), s.Availability)
+	if s.ReadAvailability != nil {
+		fmt.Fprintf(&line, ", read availability [%f]", *s.ReadAvailability)
+	}
+	fmt.Fprintf(&line, ", dropped [%d]", s.Dropped)
+	for _, op := range sortedKeys(s.Operations) {
+		totals := s.Operations[op]
+		fmt.Fprintf(&line, ", %s [%d ok, %d failed, %d info", op, totals.OK, totals.Failed, totals.Info)
+		if totals.Rejected > 0 {
+			fmt.Fprintf(&line, ", %d rejected", totals.Rejected)
+		}
+		line.WriteString("]")
+	}
+	for _, kind := range sortedKeys(s.Anomalies) {
+		fmt.Fprintf(&line, ", %s [%d]", kind, s.Anomalies[kind])
+	}
+	if d := s.Durability; d != nil {
+		health := "healthy"
+		if !d.Healthy {
+			health = "unhealthy"
+		}
+		fmt.Fprintf(&line, ", final read [%s after %s: %d intact, %d lost, %d stale, %d unexpected, %d unreadable]",
+			health, msToDuration(d.WaitMs).Round(time.Millisecond), d.Intact, d.Lost, d.Stale, d.Unexpected, d.Unreadable)
+	}
+	if len(s.Recovered) > 0 {
+		fmt.Fprintf(&line, ", recovered [%d writes]", len(s.Recovered))
+	}
+	if s.Linearizable != "" {
+		fmt.Fprintf(&line, ", linearizable [%s]", s.Linearizable)
+	}
+	if b := s.Bank; b != nil {
+		final := "unreadable"
+		if b.FinalTotal != nil {
+			final = fmt.Sprint(*b.FinalTotal)
+		}
+		health := "healthy"
+		if !b.Healthy {
+			health = "unhealthy"
+		}
+		fmt.Fprintf(&line, ", bank [total %d, %d snapshots, %d wrong totals, %d negative, final total %s when %s after %s]",
+			b.Total, b.Snapshots, b.WrongTotals, b.Negative, final, health, msToDuration(b.WaitMs).Round(time.Millisecond))
+	}
+	if i := s.Isolation; i != nil {
+		fmt.Fprintf(&line, ", isolation [%s", i.Level)
+		for _, r := range i.Results {
+			promise := "allowed"
+			switch {
+			case !r.Kept:
+				promise = "VIOLATED"
+			case r.Prevented:
+				promise = "prevented"
+			}
+			fmt.Fprintf(&line, ", %s %s: %d of %d observed, %d aborted, %d failed",
+				r.Anomaly, promise, r.Observed, r.Trials, r.Aborted, r.Failed)
+		}
+		line.WriteString("]")
+	}
+	if l := s.ListAppend; l != nil {
+		fmt.Fprintf(&line, ", list-append [%d txns on %d keys, %d recovered, %s]", l.Txns, l.Keys, l.Recovered, l.Valid)
+	}
+	if t := s.Set; t != nil {
+		read := "none"
+		if t.Read != nil {
+			read = fmt.Sprint(*t.Read)
+		}
+		health := "healthy"
+		if !t.Healthy {
+			health = "unhealthy"
+		}
+		fmt.Fprintf(&line, ", set [%s read when %s after %s, %d lost, %d unexpected, %d recovered, %s]",
+			read, health, msToDuration(t.WaitMs).Round(time.Millisecond), t.Lost, t.Unexpected, t.Recovered, t.Valid)
+	}
+	if s.Passed {
+		line.WriteString(", PASSED")
+	} else {
+		fmt.Fprintf(&line, ", FAILED [%s]", strings.Join(s.Violations, "; "))
+	}
+	r.printf("%s\n", line.String())
+}
+
+// JSONReporter writes one JSON object per line.
+type JSONReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(w)}
+}
+
+func (r *JSONReporter) write(v any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %s\n", err)
+	}
+}
+
+func (r *JSONReporter) Operation(e OperationEvent) {
+	e.Type = "operation"
+	r.write(e)
+}
+
+func (r *JSONReporter) Interval(e IntervalEvent) {
+	e.Type = "interval"
+	r.write(e)
+}
+
+func (r *JSONReporter) Rate(e RateEvent) {
+	e.Type = "rate"
+	r.write(e)
+}
+
+func (r *JSONReporter) Latency(e LatencyEvent) {
+	e.Type = "latency"
+	r.write(e)
+}
+
+func (r *JSONReporter) Anomaly(e AnomalyEvent) {
+	e.Type = "anomaly"
+	r.write(e)
+}
+
+func (r *JSONReporter) Summary(s Summary) {
+	s.Type = "summary"
+	r.write(s)
+}
+
+// MultiReporter passes everything reported to each of the reporters.
+type MultiReporter []Reporter
+
+func (m MultiReporter) Operation(e OperationEvent) {
+	for _, r := range m {
+		r.Operation(e)
+	}
+}
+
+func (m MultiReporter) Interval(e IntervalEvent) {
+	for _, r := range m {
+		r.Interval(e)
+	}
+}
+
+func (m MultiReporter) Rate(e RateEvent) {
+	for _, r := range m {
+		r.Rate(e)
+	}
+}
+
+func (m MultiReporter) Latency(e LatencyEvent) {
+	for _, r := range m {
+		r.Latency(e)
+	}
+}
+
+func (m MultiReporter) Anomaly(e AnomalyEvent) {
+	for _, r := range m {
+		r.Anomaly(e)
+	}
+}
+
+func (m MultiReporter) Summary(s Summary) {
+	for _, r := range m {
+		r.Summary(s)
+	}
+}
+
+// tally accumulates the totals of a run for the final Summary.
+type tally struct {
+	mu         sync.Mutex
+	operations map[OpType]OpTotals
+	anomalies  map[string]int
+}
+
+func newTally() *tally {
+	return &tally{
+		operations: map[OpType]OpTotals{},
+		anomalies:  map[string]int{},
+	}
+}
+
+// operation counts one operation. A rejected one counts as rejected instead
+// of failed.
+func (t *tally) operation(op OpType, outcome history.Outcome, rejected bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	totals := t.operations[op]
+	switch {
+	case rejected:
+		totals.Rejected++
+	case outcome == history.OK:
+		totals.OK++
+	case outcome == history.Fail:
+		totals.Failed++
+	case outcome == history.Info:
+		totals.Info++
+	}
+	t.operations[op] = totals
+}
+
+func (t *tally) anomaly(kind string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.anomalies[kind]++
+}
+
+// summary fills in the operation and anomaly totals of s.
+func (t *tally) summary(s Summary) Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.Operations = map[OpType]OpTotals{}
+	ok, total := 0, 0
+	readOK, reads := 0, 0
+	for op, totals := range t.operations {
+		s.Operations[op] = totals
+		answered := totals.OK + totals.Rejected
+		all := answered + totals.Failed + totals.Info
+		switch {
+		case op.mutates():
+			ok += answered
+			total += all
+		case op != OpCheck && op != OpTrial:
+			readOK += answered
+			reads += all
+		}
+	}
+	// Without any writes, e.g. in the isolation suite, none failed.
+	s.Availability = 1
+	if total > 0 {
+		s.Availability = successRate(ok, total)
+	}
+	if reads > 0 {
+		readAvailability := successRate(readOK, reads)
+		s.ReadAvailability = &readAvailability
+	}
+	s.Anomalies = map[string]int{}
+	for kind, n := range t.anomalies {
+		s.Anomalies[kind] = n
+	}
+	return s
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func msToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
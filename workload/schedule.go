@@ -0,0 +1,251 @@
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Schedule gives the intended number of writes per second at a point of
+// the run.
+type Schedule interface {
+	Rate(elapsed time.Duration) float64
+}
+
+// Constant issues writes at a fixed rate.
+type Constant float64
+
+func (c Constant) Rate(time.Duration) float64 { return float64(c) }
+
+// Ramp changes the rate linearly from From to To over Over, then stays at To.
+type Ramp struct {
+	From, To float64
+	Over     time.Duration
+}
+
+func (r Ramp) Rate(elapsed time.Duration) float64 {
+	if elapsed >= r.Over {
+		return r.To
+	}
+	return r.From + (r.To-r.From)*float64(elapsed)/float64(r.Over)
+}
+
+// Step starts at From and adds By every Every. A positive To caps the rate.
+type Step struct {
+	From, By, To float64
+	Every        time.Duration
+}
+
+func (s Step) Rate(elapsed time.Duration) float64 {
+	rate := s.From + s.By*float64(elapsed/s.Every)
+	if s.To > 0 && ((s.By > 0 && rate > s.To) || (s.By < 0 && rate < s.To)) {
+		return s.To
+	}
+	return math.Max(rate, 0)
+}
+
+// Burst runs at Base and jumps to Peak for Length at the start of every
+// Every.
+type Burst struct {
+	Base, Peak float64
+	Every      time.Duration
+	Length     time.Duration
+}
+
+func (b Burst) Rate(elapsed time.Duration) float64 {
+	if elapsed%b.Every < b.Length {
+		return b.Peak
+	}
+	return b.Base
+}
+
+// Sine oscillates around Mean by Amplitude with the given Period. Negative
+// rates are clamped to 0.
+type Sine struct {
+	Mean, Amplitude float64
+	Period          time.Duration
+}
+
+func (s Sine) Rate(elapsed time.Duration) float64 {
+	phase := 2 * math.Pi * float64(elapsed) / float64(s.Period)
+	return math.Max(s.Mean+s.Amplitude*math.Sin(phase), 0)
+}
+
+// Trace replays one rate per second. After the last second the last rate is
+// kept.
+type Trace []float64
+
+func (t Trace) Rate(elapsed time.Duration) float64 {
+	i := int(elapsed / time.Second)
+	if i >= len(t) {
+		i = len(t) - 1
+	}
+	return t[i]
+}
+
+// LoadTrace reads a trace file with one rate per line. Empty lines and lines
+// starting with '#' are skipped.
+func LoadTrace(path string) (Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var trace Trace
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(text, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("%s:%d: invalid rate %q", path, line, text)
+		}
+		trace = append(trace, rate)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(trace) == 0 {
+		return nil, fmt.Errorf("%s: trace is empty", path)
+	}
+	return trace, nil
+}
+
+// ParseSchedule parses a schedule spec of the form "<shape>:<key>=<value>,...":
+//
+//	constant:rate=10
+//	ramp:from=10,to=100,over=5m
+//	step:from=10,by=10,every=1m,to=100
+//	burst:base=10,peak=100,every=1m,for=5s
+//	sine:mean=50,amplitude=40,period=2m
+//	trace:file=/data/rates.txt
+func ParseSchedule(spec string) (Schedule, error) {
+	shape, p, err := parseSpec("schedule", spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Schedule
+	switch shape {
+	case "constant":
+		s = Constant(p.rate("rate", true))
+	case "ramp":
+		s = Ramp{From: p.rate("from", true), To: p.rate("to", true), Over: p.duration("over")}
+	case "step":
+		s = Step{From: p.rate("from", true), By: p.float("by"), To: p.rate("to", false), Every: p.duration("every")}
+	case "burst":
+		s = Burst{Base: p.rate("base", true), Peak: p.rate("peak", true), Every: p.duration("every"), Length: p.duration("for")}
+	case "sine":
+		s = Sine{Mean: p.rate("mean", true), Amplitude: p.float("amplitude"), Period: p.duration("period")}
+	case "trace":
+		if file, ok := p.lookup("file", true); ok {
+			trace, err := LoadTrace(file)
+			if err != nil {
+				return nil, err
+			}
+			s = trace
+		}
+	default:
+		return nil, fmt.Errorf("schedule %q: unknown shape %q", spec, shape)
+	}
+	if err := p.done(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// specParams looks up the parameters of a spec of the form
+// "<name>:<key>=<value>,..." and remembers the first error, so a parser can
+// build a value in one expression.
+type specParams struct {
+	kind   string
+	spec   string
+	params map[string]string
+	used   map[string]bool
+	err    error
+}
+
+// parseSpec splits spec into its name and parameters. kind names what spec
+// describes in errors.
+func parseSpec(kind, spec string) (string, *specParams, error) {
+	name, args, _ := strings.Cut(spec, ":")
+	params := map[string]string{}
+	if args != "" {
+		for _, kv := range strings.Split(args, ",") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return "", nil, fmt.Errorf("%s %q: expected key=value, got %q", kind, spec, kv)
+			}
+			params[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return name, &specParams{kind: kind, spec: spec, params: params, used: map[string]bool{}}, nil
+}
+
+// done returns the first error, or an error for a parameter that was never
+// looked up.
+func (p *specParams) done() error {
+	if p.err != nil {
+		return p.err
+	}
+	for k := range p.params {
+		if !p.used[k] {
+			return fmt.Errorf("%s %q: unknown parameter %q", p.kind, p.spec, k)
+		}
+	}
+	return nil
+}
+
+func (p *specParams) lookup(key string, required bool) (string, bool) {
+	p.used[key] = true
+	v, ok := p.params[key]
+	if !ok && required && p.err == nil {
+		p.err = fmt.Errorf("%s %q: missing %s", p.kind, p.spec, key)
+	}
+	return v, ok
+}
+
+func (p *specParams) float(key string) float64 {
+	v, ok := p.lookup(key, true)
+	if !ok {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s %q: %s: %w", p.kind, p.spec, key, err)
+	}
+	return f
+}
+
+func (p *specParams) rate(key string, required bool) float64 {
+	if _, ok := p.lookup(key, required); !ok {
+		return 0
+	}
+	f := p.float(key)
+	if f < 0 && p.err == nil {
+		p.err = fmt.Errorf("%s %q: %s must not be negative", p.kind, p.spec, key)
+	}
+	return f
+}
+
+func (p *specParams) duration(key string) time.Duration {
+	v, ok := p.lookup(key, true)
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s %q: %s: %w", p.kind, p.spec, key, err)
+	}
+	return d
+}
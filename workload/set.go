@@ -0,0 +1,231 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tylergu/workloads/workload/checker"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+// SetDriver is implemented by backends that can run the set workload. Every
+// instance adds to a set of its own, identified by its index.
+type SetDriver interface {
+	Driver
+	// SetupSet creates set if needed and empties it.
+	SetupSet(ctx context.Context, set int) error
+	// Add adds element to set.
+	Add(ctx context.Context, set, element int) error
+	// ReadSet returns every element of set.
+	ReadSet(ctx context.Context, set int) ([]int, error)
+}
+
+// SetReport summarizes the final read of the set workload.
+type SetReport struct {
+	// Healthy tells whether the probe passed, after WaitMs, before the
+	// final read once the adds stopped, and Read is the number of elements
+	// it returned, nil if the set could not be read.
+	Healthy bool    `json:"healthy"`
+	WaitMs  float64 `json:"wait_ms"`
+	Read    *int    `json:"read,omitempty"`
+	// Lost and Unexpected count the elements the set checker reported, and
+	// Recovered the adds with an unknown outcome that were read.
+	Lost       int              `json:"lost"`
+	Unexpected int              `json:"unexpected"`
+	Recovered  int              `json:"recovered"`
+	Valid      checker.Validity `json:"valid"`
+}
+
+// Set adds a unique element to a grow-only set per operation, at the
+// scheduled rate. Once the adds stopped and the backend is healthy, it reads
+// the whole set and the set checker reports every acknowledged element that
+// is missing, every element that was never added or whose add failed, and
+// the adds with an unknown outcome that were applied.
+type Set struct {
+	w      *Workload
+	driver SetDriver
+
+	mu  sync.Mutex
+	ops []history.Op
+	// healthy and waited are the outcome of the probe before the final
+	// read.
+	healthy bool
+	waited  time.Duration
+}
+
+// NewSet returns a set workload. Of cfg, it uses the settings of the load,
+// its reporting and thresholds, the probe before the final read, the history
+// and the instance; the settings of the keys and their checks do not apply.
+func NewSet(driver SetDriver, cfg Config) *Set {
+	return &Set{
+		w:      New(driver, cfg),
+		driver: driver,
+	}
+}
+
+// element returns the element the add of sequence adds, unique across the
+// instances.
+func (s *Set) element(sequence int) int {
+	return sequence*s.w.cfg.Instances + s.w.cfg.Instance
+}
+
+func (s *Set) Run(ctx context.Context) error {
+	w := s.w
+	if err := w.cfg.Validate(); err != nil {
+		return err
+	}
+	if err := s.driver.SetupSet(ctx, w.cfg.Instance); err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
+
+	start, dropped, err := w.drive(ctx, loop{do: s.do})
+	if err != nil {
+		return err
+	}
+	// The final read outlives ctx, like the adds.
+	s.final(context.WithoutCancel(ctx))
+	report, err := s.check()
+	if err != nil {
+		return err
+	}
+	return w.finish(w.tally.summary(Summary{
+		Backend: w.cfg.Backend,
+		Client:  w.cfg.Client,
+		Start:   start,
+		End:     time.Now(),
+		Dropped: dropped,
+		Set:     report,
+	}))
+}
+
+// do runs the add of o and records it.
+func (s *Set) do(ctx context.Context, worker int, o op) Result {
+	w := s.w
+	element := s.element(o.sequence)
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	err := s.driver.Add(ctx, w.cfg.Instance, element)
+	end := time.Now()
+	outcome := classify(s.driver, err)
+	w.measure(OpAdd, element, &element, start, end, outcome, err)
+
+	add := history.Op{
+		F:        history.Add,
+		Key:      element,
+		Value:    history.IntPtr(element),
+		Outcome:  outcome,
+		Invoke:   start,
+		Complete: end,
+	}
+	if err != nil {
+		add.Error = err.Error()
+	}
+	s.append(w.recordOp(worker, add))
+	return Result{err: err, ts: o.ts}
+}
+
+// final waits for the backend to become healthy and reads the set, retrying
+// failed reads a few times. Every read may take FinalReadTimeout.
+func (s *Set) final(ctx context.Context) {
+	w := s.w
+	healthy, waited := w.waitHealthy(ctx)
+	s.mu.Lock()
+	s.healthy, s.waited = healthy, waited
+	s.mu.Unlock()
+	worker := len(w.processes) - 1
+	for attempt := 0; attempt < finalReadAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(probeInterval)
+		}
+		readCtx, cancel := context.WithTimeout(ctx, w.cfg.FinalReadTimeout)
+		start := time.Now()
+		elements, err := s.driver.ReadSet(readCtx, w.cfg.Instance)
+		cancel()
+		end := time.Now()
+		outcome := history.OK
+		if err != nil {
+			outcome = history.Fail
+		}
+		w.measure(OpReadSet, 0, nil, start, end, outcome, err)
+
+		read := history.Op{
+			F:        history.ReadSet,
+			Outcome:  outcome,
+			Invoke:   start,
+			Complete: end,
+		}
+		if err != nil {
+			read.Error = err.Error()
+		}
+		// The set may hold the elements of other instances, they are
+		// checked by their own.
+		for _, element := range elements {
+			if element%w.cfg.Instances == w.cfg.Instance {
+				read.Set = append(read.Set, element)
+			}
+		}
+		sort.Ints(read.Set)
+		s.append(w.recordOp(worker, read))
+		if err == nil {
+			return
+		}
+	}
+}
+
+func (s *Set) append(op history.Op) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op.Index = len(s.ops)
+	s.ops = append(s.ops, op)
+}
+
+// check runs the set checker over the adds and the final read, read back
+// from the history if one was recorded so the reported indices match it,
+// and reports its anomalies.
+func (s *Set) check() (*SetReport, error) {
+	w := s.w
+	s.mu.Lock()
+	ops := s.ops
+	report := &SetReport{Healthy: s.healthy, WaitMs: durationToMs(s.waited)}
+	s.mu.Unlock()
+	if w.cfg.History != nil {
+		var err error
+		if ops, err = w.cfg.History.Ops(); err != nil {
+			return nil, fmt.Errorf("loading history: %w", err)
+		}
+	} else {
+		sort.SliceStable(ops, func(i, j int) bool { return ops[i].Invoke.Before(ops[j].Invoke) })
+	}
+
+	result := checker.Set(ops)
+	for _, a := range result.Anomalies {
+		inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+		w.tally.anomaly(a.Kind)
+		w.reporter.Anomaly(AnomalyEvent{
+			Time:    time.Now(),
+			Kind:    a.Kind,
+			Key:     a.Key,
+			Message: a.Message,
+			Ops:     a.Ops,
+		})
+		switch a.Kind {
+		case checker.AnomalyLostElement:
+			report.Lost++
+		case checker.AnomalyUnexpectedElement:
+			report.Unexpected++
+		}
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		if ops[i].F == history.ReadSet && ops[i].Outcome == history.OK {
+			report.Read = history.IntPtr(len(ops[i].Set))
+			break
+		}
+	}
+	report.Recovered = len(result.Recovered)
+	report.Valid = result.Valid
+	return report, nil
+}
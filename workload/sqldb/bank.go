@@ -0,0 +1,95 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+// GetPlayersSQL reads every account of the bank workload.
+const GetPlayersSQL = "SELECT id, coins FROM player"
+
+var _ workload.BankDriver = &Driver{}
+
+// SetupAccounts recreates the `player` table with one player per account,
+// holding the balance in `coins`.
+func (d *Driver) SetupAccounts(ctx context.Context, accounts, balance int) error {
+	if _, err := d.db.ExecContext(ctx, DropTableSQL); err != nil {
+		return err
+	}
+	if _, err := d.db.ExecContext(ctx, CreateTableSQL); err != nil {
+		return err
+	}
+	for account := 0; account < accounts; account++ {
+		if _, err := d.db.ExecContext(ctx, CreatePlayerSQL, playerID(account), balance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transfer reads both balances with locking reads and writes the new ones
+// back, so a transfer that is not isolated from a concurrent one creates or
+// destroys coins.
+func (d *Driver) Transfer(ctx context.Context, from, to, amount int) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The rows are locked in the same order by every transfer, so two
+	// transfers between the same accounts cannot deadlock.
+	balances := map[int]int{}
+	for _, account := range []int{min(from, to), max(from, to)} {
+		var id string
+		var coins int
+		if err := tx.QueryRowContext(ctx, GetPlayerWithLockSQL, playerID(account)).Scan(&id, &coins); err != nil {
+			return err
+		}
+		balances[account] = coins
+	}
+	if balances[from] < amount {
+		return workload.ErrInsufficientFunds
+	}
+	if _, err := tx.ExecContext(ctx, UpdatePlayerSQL, balances[from]-amount, playerID(from)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, UpdatePlayerSQL, balances[to]+amount, playerID(to)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Balances reads every account in one read-only transaction.
+func (d *Driver) Balances(ctx context.Context) (map[int]int, error) {
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, GetPlayersSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := map[int]int{}
+	for rows.Next() {
+		var id string
+		var coins int
+		if err := rows.Scan(&id, &coins); err != nil {
+			return nil, err
+		}
+		account, err := playerTable.parseID(id)
+		if err != nil {
+			return nil, err
+		}
+		balances[account] = coins
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return balances, tx.Commit()
+}
@@ -0,0 +1,314 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+const (
+	DropIsolationSQL   = "DROP TABLE IF EXISTS isolation"
+	CreateIsolationSQL = "CREATE TABLE isolation ( `id` INTEGER, `v` INTEGER, PRIMARY KEY (`id`) )"
+	InsertIsolationSQL = "INSERT INTO isolation (id, v) VALUES (?, ?), (?, ?)"
+	GetIsolationSQL    = "SELECT v FROM isolation WHERE id = ?"
+	UpdateIsolationSQL = "UPDATE isolation SET v = ? WHERE id = ?"
+	GetVersionSQL      = "SELECT VERSION()"
+)
+
+// stepWait is how long a step of a trial may block, e.g. on a lock held by
+// the other transaction, before the next step of the script starts anyway.
+const stepWait = 100 * time.Millisecond
+
+var _ workload.IsolationDriver = &Driver{}
+
+var isolationLevels = map[workload.IsolationLevel]sql.IsolationLevel{
+	workload.ReadUncommitted: sql.LevelReadUncommitted,
+	workload.ReadCommitted:   sql.LevelReadCommitted,
+	workload.RepeatableRead:  sql.LevelRepeatableRead,
+	workload.Serializable:    sql.LevelSerializable,
+}
+
+// tidbUnsupportedLevels are the isolation levels TiDB rejects unless
+// tidb_skip_isolation_level_check is set, and then runs at a level of its own.
+var tidbUnsupportedLevels = map[workload.IsolationLevel]bool{
+	workload.ReadUncommitted: true,
+	workload.Serializable:    true,
+}
+
+// allowedAnomalies are the anomalies InnoDB and TiDB let through at a level that
+// promises to prevent them. Their repeatable read reads a snapshot, but
+// writes on the latest committed row, so two transactions that read and
+// increment a row both commit and one increment is lost.
+var allowedAnomalies = map[workload.IsolationLevel][]string{
+	workload.RepeatableRead: {workload.AnomalyLostUpdate},
+}
+
+// IsolationPromises rejects the levels TiDB does not implement, so the suite
+// does not report trials that all failed to start or ran at another level,
+// and leaves out what InnoDB and TiDB do not prevent at the level.
+func (d *Driver) IsolationPromises(ctx context.Context, level workload.IsolationLevel) ([]string, error) {
+	var version string
+	if err := d.db.QueryRowContext(ctx, GetVersionSQL).Scan(&version); err != nil {
+		return nil, err
+	}
+	if strings.Contains(version, "TiDB") && tidbUnsupportedLevels[level] {
+		return nil, fmt.Errorf("TiDB %s does not support the %s isolation level", version, level)
+	}
+	var promises []string
+	for _, anomaly := range level.Promises() {
+		weak := false
+		for _, w := range allowedAnomalies[level] {
+			weak = weak || w == anomaly
+		}
+		if !weak {
+			promises = append(promises, anomaly)
+		}
+	}
+	return promises, nil
+}
+
+// SetupIsolation recreates the `isolation` table. Every trial inserts two
+// rows of its own, x and y.
+func (d *Driver) SetupIsolation(ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, DropIsolationSQL); err != nil {
+		return err
+	}
+	_, err := d.db.ExecContext(ctx, CreateIsolationSQL)
+	return err
+}
+
+// Trial runs the script of anomaly with two transactions, each on its own
+// connection, the way the Hermitage tests do by hand. The steps alternate
+// between the transactions in the order of the script; a step that blocks is
+// left running while the script continues, and the transaction runs its
+// later steps once it unblocks.
+func (d *Driver) Trial(ctx context.Context, anomaly string, level workload.IsolationLevel, id int) (workload.Trial, error) {
+	x, y := 2*id, 2*id+1
+	var initial [2]int
+	switch anomaly {
+	case workload.AnomalyWriteSkew:
+		initial = [2]int{1, 1}
+	case workload.AnomalyReadSkew:
+		initial = [2]int{50, 50}
+	case workload.AnomalyG1c:
+		initial = [2]int{10, 20}
+	}
+	if _, err := d.db.ExecContext(ctx, InsertIsolationSQL, x, initial[0], y, initial[1]); err != nil {
+		return workload.Trial{}, err
+	}
+
+	t1, err := d.begin(ctx, level)
+	if err != nil {
+		return workload.Trial{}, err
+	}
+	t2, err := d.begin(ctx, level)
+	if err != nil {
+		t1.end()
+		return workload.Trial{}, err
+	}
+
+	var trial workload.Trial
+	switch anomaly {
+	case workload.AnomalyDirtyRead:
+		// T2 reads x while T1, which wrote it, has not finished, and T1
+		// rolls back.
+		var x2 int
+		t1.step(write(x, 1))
+		t2.step(read(x, &x2))
+		t2.step(commit)
+		t1.step(rollback)
+		if err := finish(t1, t2); err != nil {
+			return workload.Trial{}, err
+		}
+		trial.Observed = t2.err == nil && x2 == 1
+		trial.Detail = fmt.Sprintf("read x = %d of a write of 1 that was rolled back", x2)
+	case workload.AnomalyG1c:
+		// Each transaction writes one row and reads the other's.
+		var x2, y1 int
+		t1.step(write(x, 11))
+		t2.step(write(y, 22))
+		t1.step(read(y, &y1))
+		t2.step(read(x, &x2))
+		t1.step(commit)
+		t2.step(commit)
+		if err := finish(t1, t2); err != nil {
+			return workload.Trial{}, err
+		}
+		trial.Observed = t1.err == nil && t2.err == nil && y1 == 22 && x2 == 11
+		trial.Detail = fmt.Sprintf("T1 wrote x = 11 and read y = %d, T2 wrote y = 22 and read x = %d", y1, x2)
+	case workload.AnomalyLostUpdate:
+		// Both transactions increment x by what they read.
+		var x1, x2 int
+		t1.step(read(x, &x1))
+		t2.step(read(x, &x2))
+		t1.step(func(ctx context.Context, tx *sql.Tx) error { return write(x, x1+1)(ctx, tx) })
+		t1.step(commit)
+		t2.step(func(ctx context.Context, tx *sql.Tx) error { return write(x, x2+1)(ctx, tx) })
+		t2.step(commit)
+		if err := finish(t1, t2); err != nil {
+			return workload.Trial{}, err
+		}
+		final, err := d.isolationValue(ctx, x)
+		if err != nil {
+			return workload.Trial{}, err
+		}
+		trial.Observed = t1.err == nil && t2.err == nil && final != 2
+		trial.Detail = fmt.Sprintf("both incremented x from 0 and committed, x is %d", final)
+	case workload.AnomalyReadSkew:
+		// T2 moves 25 from x to y while T1 reads x before and y after.
+		var x1, y1 int
+		t1.step(read(x, &x1))
+		t2.step(write(x, 25))
+		t2.step(write(y, 75))
+		t2.step(commit)
+		t1.step(read(y, &y1))
+		t1.step(commit)
+		if err := finish(t1, t2); err != nil {
+			return workload.Trial{}, err
+		}
+		trial.Observed = t1.err == nil && x1+y1 != 100
+		trial.Detail = fmt.Sprintf("read x = %d and y = %d, which add up to %d instead of 100", x1, y1, x1+y1)
+	case workload.AnomalyWriteSkew:
+		// Both transactions check that x + y >= 2 before setting one of
+		// them to 0, so together they should keep x + y >= 1.
+		var x1, y1, x2, y2 int
+		t1.step(read(x, &x1))
+		t1.step(read(y, &y1))
+		t2.step(read(x, &x2))
+		t2.step(read(y, &y2))
+		t1.step(func(ctx context.Context, tx *sql.Tx) error {
+			if x1+y1 < 2 {
+				return nil
+			}
+			return write(x, 0)(ctx, tx)
+		})
+		t2.step(func(ctx context.Context, tx *sql.Tx) error {
+			if x2+y2 < 2 {
+				return nil
+			}
+			return write(y, 0)(ctx, tx)
+		})
+		t1.step(commit)
+		t2.step(commit)
+		if err := finish(t1, t2); err != nil {
+			return workload.Trial{}, err
+		}
+		finalX, err := d.isolationValue(ctx, x)
+		if err != nil {
+			return workload.Trial{}, err
+		}
+		finalY, err := d.isolationValue(ctx, y)
+		if err != nil {
+			return workload.Trial{}, err
+		}
+		trial.Observed = t1.err == nil && t2.err == nil && finalX+finalY < 1
+		trial.Detail = fmt.Sprintf("both saw x + y = 2 and committed, x = %d and y = %d", finalX, finalY)
+	default:
+		t1.end()
+		t2.end()
+		return workload.Trial{}, fmt.Errorf("unknown anomaly %q", anomaly)
+	}
+	trial.Aborted = t1.err != nil || t2.err != nil
+	return trial, nil
+}
+
+func (d *Driver) isolationValue(ctx context.Context, id int) (int, error) {
+	var v int
+	err := d.db.QueryRowContext(ctx, GetIsolationSQL, id).Scan(&v)
+	return v, err
+}
+
+// session runs the steps of one transaction of a trial in order, on a
+// goroutine of its own, so a step that blocks does not block the script.
+// Once a step fails, the transaction is aborted and its later steps are
+// skipped; err holds the error once the session ended.
+type session struct {
+	steps chan sessionStep
+	ended chan struct{}
+	err   error
+}
+
+type sessionStep struct {
+	run  func(ctx context.Context, tx *sql.Tx) error
+	done chan struct{}
+}
+
+func (d *Driver) begin(ctx context.Context, level workload.IsolationLevel) (*session, error) {
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolationLevels[level]})
+	if err != nil {
+		return nil, err
+	}
+	s := &session{steps: make(chan sessionStep, 8), ended: make(chan struct{})}
+	go func() {
+		defer close(s.ended)
+		for step := range s.steps {
+			if s.err == nil {
+				s.err = step.run(ctx, tx)
+			}
+			close(step.done)
+		}
+		tx.Rollback()
+	}()
+	return s, nil
+}
+
+// step queues run and waits until it completed, or for stepWait if it
+// blocks.
+func (s *session) step(run func(ctx context.Context, tx *sql.Tx) error) {
+	done := make(chan struct{})
+	s.steps <- sessionStep{run: run, done: done}
+	select {
+	case <-done:
+	case <-time.After(stepWait):
+	}
+}
+
+// end waits until every step of s ran and the transaction ended.
+func (s *session) end() {
+	close(s.steps)
+	<-s.ended
+}
+
+// finish ends both sessions. A transaction the database aborted, e.g. on a
+// deadlock or serialization failure, keeps its error in the session; any
+// other error means the trial could not run.
+func finish(sessions ...*session) error {
+	for _, s := range sessions {
+		s.end()
+	}
+	for _, s := range sessions {
+		var mysqlErr *mysql.MySQLError
+		if s.err != nil && !errors.As(s.err, &mysqlErr) {
+			return s.err
+		}
+	}
+	return nil
+}
+
+func read(id int, v *int) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, GetIsolationSQL, id).Scan(v)
+	}
+}
+
+func write(id, v int) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, UpdateIsolationSQL, v, id)
+		return err
+	}
+}
+
+func commit(_ context.Context, tx *sql.Tx) error {
+	return tx.Commit()
+}
+
+func rollback(_ context.Context, tx *sql.Tx) error {
+	return tx.Rollback()
+}
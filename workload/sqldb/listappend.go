@@ -0,0 +1,75 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/tylergu/workloads/workload"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+const (
+	DropListsSQL   = "DROP TABLE IF EXISTS lists"
+	CreateListsSQL = "CREATE TABLE lists ( `id` INTEGER, `val` TEXT, PRIMARY KEY (`id`) )"
+	AppendListSQL  = "INSERT INTO lists (id, val) VALUES (?, ?) ON DUPLICATE KEY UPDATE val = CONCAT(val, ',', VALUES(val))"
+	GetListSQL     = "SELECT val FROM lists WHERE id = ?"
+)
+
+var _ workload.ListAppendDriver = &Driver{}
+
+// SetupLists recreates the `lists` table, which holds every list as the
+// comma-separated values of a text column.
+func (d *Driver) SetupLists(ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, DropListsSQL); err != nil {
+		return err
+	}
+	_, err := d.db.ExecContext(ctx, CreateListsSQL)
+	return err
+}
+
+// Txn runs mops in one transaction at the default isolation level of the
+// database.
+func (d *Driver) Txn(ctx context.Context, mops []history.Mop) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, m := range mops {
+		if m.F == history.Append {
+			if _, err := tx.ExecContext(ctx, AppendListSQL, m.Key, strconv.Itoa(*m.Value)); err != nil {
+				return err
+			}
+			continue
+		}
+		var val string
+		err := tx.QueryRowContext(ctx, GetListSQL, m.Key).Scan(&val)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if mops[i].List, err = parseList(val); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// parseList parses the comma-separated values of a list.
+func parseList(val string) ([]int, error) {
+	list := []int{}
+	if val == "" {
+		return list, nil
+	}
+	for _, part := range strings.Split(val, ",") {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
@@ -0,0 +1,49 @@
+package sqldb
+
+import (
+	"context"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+const (
+	CreateSetSQL = "CREATE TABLE IF NOT EXISTS set_elements ( `set_id` INTEGER, `element` INTEGER, PRIMARY KEY (`set_id`, `element`) )"
+	ClearSetSQL  = "DELETE FROM set_elements WHERE set_id = ?"
+	AddSetSQL    = "INSERT INTO set_elements (set_id, element) VALUES (?, ?)"
+	GetSetSQL    = "SELECT element FROM set_elements WHERE set_id = ?"
+)
+
+var _ workload.SetDriver = &Driver{}
+
+// SetupSet creates the `set_elements` table, shared by the sets of every
+// instance, and deletes the rows of set.
+func (d *Driver) SetupSet(ctx context.Context, set int) error {
+	if _, err := d.db.ExecContext(ctx, CreateSetSQL); err != nil {
+		return err
+	}
+	_, err := d.db.ExecContext(ctx, ClearSetSQL, set)
+	return err
+}
+
+// Add inserts element as a row of set.
+func (d *Driver) Add(ctx context.Context, set, element int) error {
+	_, err := d.db.ExecContext(ctx, AddSetSQL, set, element)
+	return err
+}
+
+func (d *Driver) ReadSet(ctx context.Context, set int) ([]int, error) {
+	rows, err := d.db.QueryContext(ctx, GetSetSQL, set)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var elements []int
+	for rows.Next() {
+		var element int
+		if err := rows.Scan(&element); err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, rows.Err()
+}
@@ -0,0 +1,187 @@
+// Package sqldb implements the workload driver for MySQL-compatible
+// databases such as TiDB and MariaDB.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+const (
+	CreatePlayerSQL      = "INSERT INTO player (id, coins) VALUES (?, ?)"
+	GetPlayerSQL         = "SELECT id, coins FROM player WHERE id = ?"
+	GetCountSQL          = "SELECT count(*) FROM player"
+	GetPlayerWithLockSQL = GetPlayerSQL + " FOR UPDATE"
+	UpdatePlayerSQL      = "UPDATE player set coins = ? WHERE id = ?"
+	UpsertPlayerSQL      = CreatePlayerSQL + " ON DUPLICATE KEY UPDATE coins = VALUES(coins)"
+	DeletePlayerSQL      = "DELETE FROM player WHERE id = ?"
+	ScanPlayersSQL       = "SELECT id, coins FROM player WHERE id >= ? AND id < ? ORDER BY id"
+	DropTableSQL         = "DROP TABLE IF EXISTS player"
+	CreateTableSQL       = "CREATE TABLE player ( `id` VARCHAR(36), `coins` INTEGER, `goods` INTEGER, PRIMARY KEY (`id`) );"
+)
+
+type Config struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	DBName   string
+	UseSSL   string
+	// Record switches the driver from the `player` table to the shared
+	// record schema.
+	Record *workload.Record
+}
+
+func (c Config) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&tls=%s",
+		c.User, c.Password, c.Host, c.Port, c.DBName, c.UseSSL)
+}
+
+// Driver writes the workload into the `player` table, one row per key with
+// the value stored in `coins`, or into the table of the Record schema.
+type Driver struct {
+	db    *sql.DB
+	table table
+}
+
+var (
+	_ workload.Driver   = &Driver{}
+	_ workload.Inserter = &Driver{}
+	_ workload.Updater  = &Driver{}
+	_ workload.Deleter  = &Driver{}
+	_ workload.Scanner  = &Driver{}
+	_ workload.Pinger   = &Driver{}
+
+	_ workload.ErrorClassifier = &Driver{}
+)
+
+// errDuplicateEntry is the MySQL error number of a duplicate primary key.
+const errDuplicateEntry = 1062
+
+func NewDriver(cfg Config) (*Driver, error) {
+	db, err := sql.Open("mysql", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+	db.SetConnMaxLifetime(time.Minute * 3)
+	t := playerTable
+	if cfg.Record != nil {
+		t = recordTable(*cfg.Record)
+	}
+	return &Driver{db: db, table: t}, nil
+}
+
+func (d *Driver) Setup(ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, d.table.drop); err != nil {
+		return err
+	}
+	if _, err := d.db.ExecContext(ctx, d.table.create); err != nil {
+		return err
+	}
+	return nil
+}
+
+// playerID zero-pads key so the ids sort like the keys and a range of keys
+// is a range of ids.
+func playerID(key int) string {
+	return fmt.Sprintf("player-%010d", key)
+}
+
+func (d *Driver) Write(ctx context.Context, key, value int) error {
+	_, err := d.db.ExecContext(ctx, d.table.upsert, d.table.row(key, value)...)
+	return err
+}
+
+func (d *Driver) Insert(ctx context.Context, key, value int) error {
+	_, err := d.db.ExecContext(ctx, d.table.insert, d.table.row(key, value)...)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry {
+		return workload.ErrExists
+	}
+	return err
+}
+
+func (d *Driver) Update(ctx context.Context, key, value int) error {
+	result, err := d.db.ExecContext(ctx, d.table.update, d.table.set(key, value)...)
+	if err != nil {
+		return err
+	}
+	// MySQL counts the rows that changed, not the ones that matched, but
+	// every write stores a new value.
+	n, err := result.RowsAffected()
+	if err == nil && n == 0 {
+		return workload.ErrNotFound
+	}
+	return err
+}
+
+func (d *Driver) Delete(ctx context.Context, key int) error {
+	_, err := d.db.ExecContext(ctx, d.table.delete, d.table.id(key))
+	return err
+}
+
+func (d *Driver) Scan(ctx context.Context, from, to int) ([]workload.KeyValue, error) {
+	rows, err := d.db.QueryContext(ctx, d.table.scan, d.table.id(from), d.table.id(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var kvs []workload.KeyValue
+	for rows.Next() {
+		var id string
+		var coins int
+		if err := rows.Scan(d.table.scanner(&id, &coins)...); err != nil {
+			return nil, err
+		}
+		key, err := d.table.parseID(id)
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, workload.KeyValue{Key: key, Value: coins})
+	}
+	return kvs, rows.Err()
+}
+
+func (d *Driver) Read(ctx context.Context, key int) (int, error) {
+	var id string
+	var coins int
+	err := d.db.QueryRowContext(ctx, d.table.get, d.table.id(key)).Scan(d.table.scanner(&id, &coins)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, workload.ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return coins, nil
+}
+
+func (d *Driver) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// Indeterminate reports whether a write or transaction may have been applied
+// despite err: the connection broke after the statement or the commit was
+// sent, e.g. in a failover, so the server may have committed it. A failure to
+// connect sent nothing.
+func (d *Driver) Indeterminate(err error) bool {
+	if errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op != "dial"
+}
+
+func (d *Driver) Close() error {
+	return d.db.Close()
+}
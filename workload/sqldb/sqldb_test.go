@@ -0,0 +1,34 @@
+package sqldb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIndeterminate(t *testing.T) {
+	d := &Driver{}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"invalid connection", mysql.ErrInvalidConn, true},
+		{"bad connection", fmt.Errorf("commit: %w", driver.ErrBadConn), true},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"broken pipe", &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}, true},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, false},
+		{"duplicate entry", &mysql.MySQLError{Number: errDuplicateEntry, Message: "Duplicate entry"}, false},
+		{"other", errors.New("syntax error"), false},
+	} {
+		if got := d.Indeterminate(tc.err); got != tc.want {
+			t.Errorf("%s: got %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package sqldb
+
+import (
+	"fmt"
+
+	"github.com/tylergu/workloads/workload"
+)
+
+// table holds the statements of the table a Driver writes to. The rows of
+// insert and upsert take the id, the value and the fields, update takes the
+// value, the fields and the id, and get and scan return the id, the value
+// and the fields.
+type table struct {
+	drop, create                              string
+	get, insert, upsert, update, delete, scan string
+	// id is the primary key of key and parseID its inverse.
+	id      func(key int) string
+	parseID func(id string) (int, error)
+	// fields returns the columns written besides the value.
+	fields func(key, value int) []any
+	// columns is the number of columns returned besides the id and value.
+	columns int
+}
+
+var playerTable = table{
+	drop:   DropTableSQL,
+	create: CreateTableSQL,
+	get:    GetPlayerSQL,
+	insert: CreatePlayerSQL,
+	upsert: UpsertPlayerSQL,
+	update: UpdatePlayerSQL,
+	delete: DeletePlayerSQL,
+	scan:   ScanPlayersSQL,
+	id:     playerID,
+	parseID: func(id string) (int, error) {
+		var key int
+		if _, err := fmt.Sscanf(id, "player-%d", &key); err != nil {
+			return 0, fmt.Errorf("parsing id %q: %w", id, err)
+		}
+		return key, nil
+	},
+	fields: func(key, value int) []any { return nil },
+}
+
+// recordTable is the table of the Record schema shared with the other
+// backends.
+func recordTable(r workload.Record) table {
+	names := make([]string, r.Fields)
+	for i := range names {
+		names[i] = r.FieldName(i)
+	}
+	columns := "`ycsb_key`, `value`"
+	placeholders := "?, ?"
+	sets := "`value` = ?"
+	updates := "`value` = VALUES(`value`)"
+	definitions := "`ycsb_key` VARCHAR(64), `value` INTEGER"
+	for _, name := range names {
+		columns += fmt.Sprintf(", `%s`", name)
+		placeholders += ", ?"
+		sets += fmt.Sprintf(", `%s` = ?", name)
+		updates += fmt.Sprintf(", `%s` = VALUES(`%s`)", name, name)
+		definitions += fmt.Sprintf(", `%s` VARCHAR(%d)", name, r.FieldLength)
+	}
+	t := workload.RecordTable
+	return table{
+		drop:    "DROP TABLE IF EXISTS " + t,
+		create:  fmt.Sprintf("CREATE TABLE %s ( %s, PRIMARY KEY (`ycsb_key`) )", t, definitions),
+		get:     fmt.Sprintf("SELECT %s FROM %s WHERE `ycsb_key` = ?", columns, t),
+		insert:  fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t, columns, placeholders),
+		upsert:  fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s", t, columns, placeholders, updates),
+		update:  fmt.Sprintf("UPDATE %s SET %s WHERE `ycsb_key` = ?", t, sets),
+		delete:  fmt.Sprintf("DELETE FROM %s WHERE `ycsb_key` = ?", t),
+		scan:    fmt.Sprintf("SELECT %s FROM %s WHERE `ycsb_key` >= ? AND `ycsb_key` < ? ORDER BY `ycsb_key`", columns, t),
+		id:      r.Key,
+		parseID: r.ParseKey,
+		fields: func(key, value int) []any {
+			fields := make([]any, r.Fields)
+			for i := range fields {
+				fields[i] = r.Field(key, value, i)
+			}
+			return fields
+		},
+		columns: r.Fields,
+	}
+}
+
+// row returns the arguments of insert and upsert.
+func (t table) row(key, value int) []any {
+	return append([]any{t.id(key), value}, t.fields(key, value)...)
+}
+
+// set returns the arguments of update.
+func (t table) set(key, value int) []any {
+	return append(append([]any{value}, t.fields(key, value)...), t.id(key))
+}
+
+// scanner returns the destinations of a row of get or scan: the id, the
+// value and the columns, which are discarded.
+func (t table) scanner(id *string, value *int) []any {
+	dest := []any{id, value}
+	for i := 0; i < t.columns; i++ {
+		dest = append(dest, new([]byte))
+	}
+	return dest
+}
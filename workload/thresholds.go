@@ -0,0 +1,53 @@
+package workload
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tylergu/workloads/workload/checker"
+)
+
+// ErrThresholdViolated is returned by Run when the run did not meet its
+// Thresholds.
+var ErrThresholdViolated = errors.New("thresholds violated")
+
+// Thresholds decide whether a run passed, so CI can gate on the exit code of
+// the workload.
+type Thresholds struct {
+	// MaxAnomalies is the number of anomalies tolerated. Negative disables
+	// the check.
+	MaxAnomalies int
+	// MinAvailability is the fraction of writes that must succeed, between 0
+	// and 1, and MinReadAvailability the one of the reads and scans of the
+	// load.
+	MinAvailability     float64
+	MinReadAvailability float64
+}
+
+func (t Thresholds) violations(s Summary) []string {
+	var violations []string
+	if t.MaxAnomalies >= 0 {
+		total := 0
+		for _, n := range s.Anomalies {
+			total += n
+		}
+		if total > t.MaxAnomalies {
+			violations = append(violations, fmt.Sprintf("%d anomalies, at most %d allowed", total, t.MaxAnomalies))
+		}
+		// A final read that never succeeded leaves nothing to check, which
+		// must not pass for a run without anomalies.
+		if s.Bank != nil && s.Bank.FinalTotal == nil {
+			violations = append(violations, "final snapshot of the balances unreadable")
+		}
+		if s.Set != nil && s.Set.Valid == checker.Unknown {
+			violations = append(violations, "final read of the set unreadable")
+		}
+	}
+	if s.Availability < t.MinAvailability {
+		violations = append(violations, fmt.Sprintf("availability %f below %f", s.Availability, t.MinAvailability))
+	}
+	if s.ReadAvailability != nil && *s.ReadAvailability < t.MinReadAvailability {
+		violations = append(violations, fmt.Sprintf("read availability %f below %f", *s.ReadAvailability, t.MinReadAvailability))
+	}
+	return violations
+}
@@ -0,0 +1,162 @@
+package workload
+
+import (
+	"time"
+)
+
+// Result is the outcome of one write.
+type Result struct {
+	err error
+	// ts is the time the write was scheduled at. Results are attributed to
+	// the bucket of ts, not to the bucket they complete in.
+	ts time.Time
+}
+
+// bucket counts the writes scheduled within one bucket of wall-clock time.
+type bucket struct {
+	ok     int
+	failed int
+}
+
+func (b bucket) total() int { return b.ok + b.failed }
+
+// windows aggregates results into fixed wall-clock buckets and summarizes
+// the most recent buckets over one or more sliding windows. Every bucket is
+// emitted, including empty ones, so an outage shows up as zero operations.
+type windows struct {
+	reporter Reporter
+	size     time.Duration
+	windows  []time.Duration
+	origin   time.Time
+
+	// open holds the buckets that have not been emitted yet, by index.
+	open map[int64]*bucket
+	// next is the index of the next bucket to emit.
+	next int64
+	// history holds the emitted buckets needed by the largest window,
+	// oldest first.
+	history []bucket
+}
+
+func newWindows(reporter Reporter, size time.Duration, sizes []time.Duration, start time.Time) *windows {
+	return &windows{
+		reporter: reporter,
+		size:     size,
+		windows:  sizes,
+		origin:   start.Truncate(size),
+		open:     map[int64]*bucket{},
+	}
+}
+
+func (w *windows) index(ts time.Time) int64 {
+	return int64(ts.Sub(w.origin) / w.size)
+}
+
+func (w *windows) add(r Result) {
+	idx := w.index(r.ts)
+	// Results that complete after their bucket has been emitted are
+	// counted in the oldest bucket that is still open.
+	if idx < w.next {
+		idx = w.next
+	}
+	b, ok := w.open[idx]
+	if !ok {
+		b = &bucket{}
+		w.open[idx] = b
+	}
+	if r.err == nil {
+		b.ok++
+	} else {
+		b.failed++
+	}
+}
+
+// emitUntil prints every bucket that ended at or before cutoff.
+func (w *windows) emitUntil(cutoff time.Time) {
+	for !w.origin.Add(time.Duration(w.next+1) * w.size).After(cutoff) {
+		w.emit()
+	}
+}
+
+// flush prints every remaining bucket that holds results.
+func (w *windows) flush() {
+	last := w.next - 1
+	for idx := range w.open {
+		if idx > last {
+			last = idx
+		}
+	}
+	for w.next <= last {
+		w.emit()
+	}
+}
+
+func (w *windows) emit() {
+	var b bucket
+	if open, ok := w.open[w.next]; ok {
+		b = *open
+		delete(w.open, w.next)
+	}
+	ts := w.origin.Add(time.Duration(w.next) * w.size)
+	w.next++
+
+	w.history = append(w.history, b)
+	if keep := w.bucketsIn(w.largest()); len(w.history) > keep {
+		w.history = w.history[len(w.history)-keep:]
+	}
+
+	e := IntervalEvent{
+		Time:        ts,
+		Ops:         b.total(),
+		OK:          b.ok,
+		Failed:      b.failed,
+		SuccessRate: successRate(b.ok, b.total()),
+	}
+	for _, window := range w.windows {
+		ok, total := w.summarize(window)
+		e.Windows = append(e.Windows, WindowSummary{
+			Window:      window.String(),
+			Ops:         total,
+			SuccessRate: successRate(ok, total),
+		})
+	}
+	w.reporter.Interval(e)
+}
+
+func (w *windows) largest() time.Duration {
+	largest := w.size
+	for _, window := range w.windows {
+		if window > largest {
+			largest = window
+		}
+	}
+	return largest
+}
+
+func (w *windows) bucketsIn(window time.Duration) int {
+	n := int((window + w.size - 1) / w.size)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// summarize sums the most recent buckets covering window.
+func (w *windows) summarize(window time.Duration) (ok, total int) {
+	n := w.bucketsIn(window)
+	if n > len(w.history) {
+		n = len(w.history)
+	}
+	for _, b := range w.history[len(w.history)-n:] {
+		ok += b.ok
+		total += b.total()
+	}
+	return ok, total
+}
+
+func successRate(ok, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(ok) / float64(total)
+}
@@ -0,0 +1,1011 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tylergu/workloads/workload/checker"
+	"github.com/tylergu/workloads/workload/history"
+)
+
+type Config struct {
+	// Backend names the system under test in metrics and reports.
+	Backend string
+
+	// Rate is the number of writes issued per second, used when no Schedule
+	// is set.
+	Rate float64
+	// Schedule varies the rate over the run.
+	Schedule Schedule
+	// Duration stops the run after the given time. Zero runs until cancelled.
+	Duration time.Duration
+	// Operations stops the run after the given number of writes. Zero means
+	// no limit.
+	Operations int
+
+	// BucketSize is the length of the wall-clock buckets results are
+	// reported in.
+	BucketSize time.Duration
+	// Windows are the sliding windows every bucket line is summarized over.
+	Windows []time.Duration
+	// KeySpace is the number of distinct keys written.
+	KeySpace int
+	// KeyDistribution picks the key of every write, see ParseDistribution.
+	// Seed seeds its random choices and the ones of Mix.
+	KeyDistribution string
+	Seed            int64
+	// Mix weighs the operation types of the load, see ParseMix. Empty
+	// issues only writes.
+	Mix string
+	// ScanLength is the number of keys a scan of the Mix reads.
+	ScanLength int
+	// Preload inserts every key of this instance once before the Mix
+	// starts, like the load phase of YCSB: the first KeySpace sequences
+	// are inserts of the keys in order.
+	Preload bool
+	// Record is the schema the drivers store the keys in, see Record. Nil
+	// keeps the schema of every driver.
+	Record *Record
+	// Client identifies this instance in the history and the summary.
+	Client string
+	// Instance is the index of this instance among Instances instances
+	// writing to the same backend. Every instance writes only to its own
+	// partition of KeySpace keys, starting at key Instance*KeySpace, and
+	// the checkers only assert on keys of their own partition.
+	Instance  int
+	Instances int
+	// CrossCheck also reads the partitions of the other instances and
+	// reports a key whose value goes back, without knowing their writes.
+	CrossCheck bool
+	// OpTimeout bounds every single Write and Read issued to the driver.
+	OpTimeout time.Duration
+	// MaxInFlight is the number of writes that may be outstanding at once.
+	MaxInFlight int
+	// QueueSize is the number of writes that may wait for a free slot once
+	// MaxInFlight is reached. Further writes are dropped and reported.
+	QueueSize int
+	// SkipUntilFirstSuccess drops results until the first successful write,
+	// so the connection warm-up does not show up as unavailability.
+	SkipUntilFirstSuccess bool
+	// Reporter receives the events of the run. Nil prints text to stdout.
+	Reporter Reporter
+	// Thresholds decide whether the run passed.
+	Thresholds Thresholds
+	// History records every write and read for offline analysis. Nil
+	// disables recording.
+	History *history.Recorder
+	// Probe decides when the backend is healthy again once writing stopped,
+	// before the final read verifies every key. Nil probes the driver.
+	Probe Probe
+	// RecoveryTimeout bounds the wait for Probe. The final read starts
+	// anyway once it passed.
+	RecoveryTimeout time.Duration
+	// FinalReadTimeout bounds the final read of a whole set by the set
+	// workload, which takes much longer than a single operation.
+	FinalReadTimeout time.Duration
+	// Checkpoint is the file the sequence and the state of every key are
+	// saved to every CheckpointInterval, e.g. on a PVC. Empty disables
+	// checkpoints.
+	Checkpoint         string
+	CheckpointInterval time.Duration
+	// Resume continues a previous run from its checkpoint instead of setting
+	// up the backend afresh.
+	Resume *Checkpoint
+	// Linearizability checks the recorded History for linearizability of
+	// every key at the end of the run, giving up after
+	// LinearizabilityTimeout.
+	Linearizability        bool
+	LinearizabilityTimeout time.Duration
+}
+
+const (
+	// probeInterval is the pause between probes of an unhealthy backend and
+	// between attempts of a final read.
+	probeInterval = time.Second
+	// finalReadAttempts is how often the final read of a key is tried.
+	finalReadAttempts = 3
+)
+
+func DefaultConfig() Config {
+	return Config{
+		Rate:       10,
+		BucketSize: time.Second,
+		Windows:    []time.Duration{10 * time.Second, time.Minute},
+		KeySpace:   1000,
+		Instances:  1,
+
+		KeyDistribution: "sequential",
+		ScanLength:      10,
+		OpTimeout:       time.Second,
+		MaxInFlight:     100,
+
+		RecoveryTimeout:    5 * time.Minute,
+		FinalReadTimeout:   30 * time.Second,
+		CheckpointInterval: 10 * time.Second,
+
+		LinearizabilityTimeout: time.Minute,
+	}
+}
+
+// Workload drives a Driver with a scheduled rate of writes, reports the
+// success rate over sliding time windows and keeps checking that no
+// acknowledged write is lost.
+type Workload struct {
+	driver   Driver
+	cfg      Config
+	reporter Reporter
+
+	keys *keys
+	dist Distribution
+	mix  *Mix
+	// last holds the done channel of the write issued last to every key.
+	// Only the issue loop uses it.
+	last      map[int]chan struct{}
+	latencies *latencies
+	// foreign holds the highest value read from every key of the other
+	// instances, for CrossCheck. Only the checker uses it.
+	foreign map[int]int
+	tally   *tally
+
+	// processes holds the history process id of every pool worker, followed
+	// by the one of the checker. Each slot is only used by its owner.
+	processes []int
+}
+
+func New(driver Driver, cfg Config) *Workload {
+	reporter := cfg.Reporter
+	if reporter == nil {
+		reporter = NewTextReporter(os.Stdout)
+	}
+	return &Workload{
+		driver:    driver,
+		cfg:       cfg,
+		reporter:  reporter,
+		keys:      newKeys(),
+		last:      map[int]chan struct{}{},
+		foreign:   map[int]int{},
+		latencies: newLatencies(),
+		tally:     newTally(),
+	}
+}
+
+func (c Config) Validate() error {
+	if c.Schedule == nil && c.Rate <= 0 {
+		return fmt.Errorf("rate must be positive, got %v", c.Rate)
+	}
+	if c.Duration < 0 {
+		return fmt.Errorf("duration must not be negative, got %s", c.Duration)
+	}
+	if c.Operations < 0 {
+		return fmt.Errorf("operations must not be negative, got %d", c.Operations)
+	}
+	if c.BucketSize <= 0 {
+		return fmt.Errorf("bucket size must be positive, got %s", c.BucketSize)
+	}
+	for _, window := range c.Windows {
+		if window < c.BucketSize {
+			return fmt.Errorf("window %s is shorter than the bucket size %s", window, c.BucketSize)
+		}
+	}
+	if c.KeySpace <= 0 {
+		return fmt.Errorf("key space must be positive, got %d", c.KeySpace)
+	}
+	if _, err := ParseDistribution(c.KeyDistribution, c.KeySpace, c.Seed); err != nil {
+		return err
+	}
+	if _, err := ParseMix(c.Mix, c.Seed); err != nil {
+		return err
+	}
+	if c.ScanLength <= 0 {
+		return fmt.Errorf("scan length must be positive, got %d", c.ScanLength)
+	}
+	if c.Record != nil {
+		if err := c.Record.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Instances <= 0 {
+		return fmt.Errorf("instances must be positive, got %d", c.Instances)
+	}
+	if c.Instance < 0 || c.Instance >= c.Instances {
+		return fmt.Errorf("instance must be between 0 and %d, got %d", c.Instances-1, c.Instance)
+	}
+	if c.MaxInFlight <= 0 {
+		return fmt.Errorf("max in-flight must be positive, got %d", c.MaxInFlight)
+	}
+	if c.QueueSize < 0 {
+		return fmt.Errorf("queue size must not be negative, got %d", c.QueueSize)
+	}
+	if c.Thresholds.MinAvailability < 0 || c.Thresholds.MinAvailability > 1 {
+		return fmt.Errorf("min availability must be between 0 and 1, got %v", c.Thresholds.MinAvailability)
+	}
+	if c.Thresholds.MinReadAvailability < 0 || c.Thresholds.MinReadAvailability > 1 {
+		return fmt.Errorf("min read availability must be between 0 and 1, got %v", c.Thresholds.MinReadAvailability)
+	}
+	if c.RecoveryTimeout < 0 {
+		return fmt.Errorf("recovery timeout must not be negative, got %s", c.RecoveryTimeout)
+	}
+	if c.FinalReadTimeout <= 0 {
+		return fmt.Errorf("final read timeout must be positive, got %s", c.FinalReadTimeout)
+	}
+	if c.Checkpoint != "" && c.CheckpointInterval <= 0 {
+		return fmt.Errorf("checkpoint interval must be positive, got %s", c.CheckpointInterval)
+	}
+	if c.Resume != nil && c.Resume.KeySpace != c.KeySpace {
+		return fmt.Errorf("key space %d differs from %d of the checkpoint", c.KeySpace, c.Resume.KeySpace)
+	}
+	if c.Resume != nil && c.Resume.Instance != c.Instance {
+		return fmt.Errorf("instance %d differs from %d of the checkpoint", c.Instance, c.Resume.Instance)
+	}
+	if c.Resume != nil && (c.Resume.KeyDistribution != c.KeyDistribution || c.Resume.Seed != c.Seed) {
+		return fmt.Errorf("key distribution %q with seed %d differs from %q with seed %d of the checkpoint",
+			c.KeyDistribution, c.Seed, c.Resume.KeyDistribution, c.Resume.Seed)
+	}
+	if c.Resume != nil && (c.Resume.Mix != c.Mix || c.Resume.Preload != c.Preload) {
+		return fmt.Errorf("mix %q with preload %t differs from %q with preload %t of the checkpoint",
+			c.Mix, c.Preload, c.Resume.Mix, c.Resume.Preload)
+	}
+	if c.Linearizability && c.History == nil {
+		return errors.New("the linearizability checker needs a history to be recorded")
+	}
+	return nil
+}
+
+// Run sets up the backend and issues writes until ctx is cancelled or the
+// configured duration or number of operations is reached. Cancelling ctx only
+// stops issuing: Run then waits for the in-flight writes and for the backend
+// to become healthy, reads every key once more and reports the summary. It
+// returns ErrThresholdViolated if the run did not meet the configured
+// Thresholds.
+func (w *Workload) Run(ctx context.Context) error {
+	if err := w.cfg.Validate(); err != nil {
+		return err
+	}
+	w.dist, _ = ParseDistribution(w.cfg.KeyDistribution, w.cfg.KeySpace, w.cfg.Seed)
+	w.mix, _ = ParseMix(w.cfg.Mix, w.cfg.Seed)
+	if w.mix != nil {
+		if err := w.mix.supports(w.driver); err != nil {
+			return err
+		}
+	}
+	if _, ok := w.driver.(Inserter); w.cfg.Preload && !ok {
+		return fmt.Errorf("the driver does not support %s operations to preload the keys", OpInsert)
+	}
+	// A resumed run keeps what the previous one wrote, and only the first
+	// instance sets up the backend for all of them.
+	if w.cfg.Resume == nil && w.cfg.Instance == 0 {
+		if err := w.driver.Setup(ctx); err != nil {
+			return fmt.Errorf("setup: %w", err)
+		}
+	}
+	sequence := 0
+	if w.cfg.Resume != nil {
+		sequence = w.cfg.Resume.Lease
+		w.resume()
+	}
+
+	l := loop{
+		first:      sequence,
+		do:         w.do,
+		background: []func(context.Context){w.check},
+		issue: func(o *op) error {
+			o.key, o.op = w.plan(o.sequence)
+			if o.op.mutates() {
+				o.after, o.done = w.last[o.key], make(chan struct{})
+				o.value = w.keys.issue(o.sequence, o.key, o.op == OpDelete, o.ts)
+			}
+			return nil
+		},
+		submitted: func(o op, accepted bool) {
+			switch {
+			case accepted && o.done != nil:
+				w.last[o.key] = o.done
+			case !accepted && o.op.mutates():
+				w.keys.drop(o.sequence)
+			}
+		},
+	}
+	var cp *checkpointer
+	if w.cfg.Checkpoint != "" {
+		cp = &checkpointer{
+			path:     w.cfg.Checkpoint,
+			keySpace: w.cfg.KeySpace,
+			instance: w.cfg.Instance,
+			dist:     w.cfg.KeyDistribution,
+			seed:     w.cfg.Seed,
+			mix:      w.cfg.Mix,
+			preload:  w.cfg.Preload,
+			lease:    sequence,
+		}
+		l.background = append(l.background, func(ctx context.Context) { w.checkpoint(ctx, cp) })
+		plan := l.issue
+		l.issue = func(o *op) error {
+			// A restarted run must not reuse a sequence that may have
+			// been issued, so sequences are leased one round over the
+			// keys at a time.
+			if err := cp.extend(o.sequence, w.cfg.KeySpace, w.keys); err != nil {
+				return err
+			}
+			return plan(o)
+		}
+	}
+
+	start, dropped, checkpointErr := w.drive(ctx, l)
+
+	// The final read outlives ctx, like the writes.
+	opCtx := context.WithoutCancel(ctx)
+	durability := w.verify(opCtx)
+	if w.cfg.CrossCheck && durability != nil {
+		w.crossCheckPass(opCtx)
+	}
+	if cp != nil {
+		if err := cp.save(w.keys); err != nil && checkpointErr == nil {
+			checkpointErr = err
+		}
+	}
+
+	var linearizable checker.Validity
+	if w.cfg.Linearizability {
+		var err error
+		if linearizable, err = w.checkLinearizability(); err != nil {
+			return err
+		}
+	}
+
+	summary := w.tally.summary(Summary{
+		Backend:      w.cfg.Backend,
+		Client:       w.cfg.Client,
+		Start:        start,
+		End:          time.Now(),
+		Dropped:      dropped,
+		Durability:   durability,
+		Recovered:    w.keys.recoveredWrites(),
+		Linearizable: linearizable,
+	})
+	summary.Violations = w.cfg.Thresholds.violations(summary)
+	summary.Passed = len(summary.Violations) == 0
+	w.reporter.Summary(summary)
+	if checkpointErr != nil {
+		return fmt.Errorf("saving checkpoint: %w", checkpointErr)
+	}
+	if !summary.Passed {
+		return fmt.Errorf("%w: %s", ErrThresholdViolated, strings.Join(summary.Violations, "; "))
+	}
+	return nil
+}
+
+// plan maps a sequence to the key of this instance it goes to and its
+// operation type. Without a Mix every operation is an upsert.
+func (w *Workload) plan(sequence int) (int, OpType) {
+	base := w.cfg.Instance * w.cfg.KeySpace
+	if w.cfg.Preload && sequence < w.cfg.KeySpace {
+		return base + sequence, OpInsert
+	}
+	op := OpUpsert
+	if w.mix != nil {
+		op = w.mix.Op(sequence)
+	}
+	return base + w.dist.Key(sequence), op
+}
+
+// deletes reports whether the load deletes keys.
+func (w *Workload) deletes() bool {
+	if w.mix == nil {
+		return false
+	}
+	for _, op := range w.mix.Ops() {
+		if op == OpDelete {
+			return true
+		}
+	}
+	return false
+}
+
+// do runs one operation of the load.
+func (w *Workload) do(ctx context.Context, worker int, o op) Result {
+	switch o.op {
+	case OpRead:
+		return w.read(ctx, worker, o)
+	case OpScan:
+		return w.scan(ctx, worker, o)
+	}
+	return w.write(ctx, worker, o)
+}
+
+func (w *Workload) write(ctx context.Context, worker int, o op) Result {
+	defer close(o.done)
+	if o.after != nil {
+		<-o.after
+	}
+	key, value := o.key, o.value
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+
+	opType := o.op
+	if w.mix == nil {
+		// The writes of the default load are labeled by whether they
+		// create the key.
+		opType = OpUpdate
+		if value == 0 {
+			opType = OpInsert
+		}
+	}
+	start := time.Now()
+	invoke := start
+	var err error
+	readFailed := false
+	switch o.op {
+	case OpInsert:
+		err = w.driver.(Inserter).Insert(ctx, key, value)
+	case OpUpdate:
+		err = w.driver.(Updater).Update(ctx, key, value)
+	case OpDelete:
+		err = w.driver.(Deleter).Delete(ctx, key)
+	case OpReadModifyWrite:
+		if err = w.readBeforeWrite(ctx, worker, key); err != nil {
+			readFailed = true
+			break
+		}
+		invoke = time.Now()
+		err = w.driver.Write(ctx, key, value)
+	default:
+		err = w.driver.Write(ctx, key, value)
+	}
+	end := time.Now()
+	outcome := classify(w.driver, err)
+	if readFailed {
+		// The write was never sent.
+		outcome = history.Fail
+	}
+	w.measure(opType, key, &value, start, end, outcome, err)
+	if !readFailed {
+		w.record(worker, historyFunc(opType), key, &value, invoke, end, outcome, err)
+	}
+	w.keys.write(o.sequence, key, value, outcome)
+
+	// A rejected write was answered, the backend was available.
+	if rejected(opType, err) {
+		err = nil
+	}
+	return Result{
+		err: err,
+		ts:  o.ts,
+	}
+}
+
+// rejected reports whether err rejects an insert of a key that exists, an
+// update of one that does not or a transfer from an account that holds too
+// little.
+func rejected(op OpType, err error) bool {
+	return (op == OpInsert && errors.Is(err, ErrExists)) || (op == OpUpdate && errors.Is(err, ErrNotFound)) ||
+		(op == OpTransfer && errors.Is(err, ErrInsufficientFunds))
+}
+
+// read reads the key of o and checks the value like the checker does.
+func (w *Workload) read(ctx context.Context, worker int, o op) Result {
+	acked := w.keys.acked(o.key)
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	actual, err := w.driver.Read(ctx, o.key)
+	switch {
+	case err == nil:
+		w.observe(worker, OpRead, o.key, &actual, start, history.OK, nil)
+		w.verifyRead(o.key, &actual, acked)
+	case errors.Is(err, ErrNotFound):
+		w.observe(worker, OpRead, o.key, nil, start, history.OK, err)
+		w.verifyRead(o.key, nil, acked)
+		err = nil
+	default:
+		w.observe(worker, OpRead, o.key, nil, start, history.Fail, err)
+	}
+	return Result{err: err, ts: o.ts}
+}
+
+// readBeforeWrite is the read of a read-modify-write, checked like the read
+// of the load. The write only starts once it succeeded.
+func (w *Workload) readBeforeWrite(ctx context.Context, worker int, key int) error {
+	acked := w.keys.acked(key)
+	start := time.Now()
+	actual, err := w.driver.Read(ctx, key)
+	end := time.Now()
+	switch {
+	case err == nil:
+		w.record(worker, history.Read, key, &actual, start, end, history.OK, nil)
+		w.verifyRead(key, &actual, acked)
+	case errors.Is(err, ErrNotFound):
+		w.record(worker, history.Read, key, nil, start, end, history.OK, err)
+		w.verifyRead(key, nil, acked)
+		err = nil
+	default:
+		w.record(worker, history.Read, key, nil, start, end, history.Fail, err)
+	}
+	return err
+}
+
+// scan reads ScanLength keys of this instance starting at the key of o, and
+// checks every value like the checker does. The history gets one read per
+// key.
+func (w *Workload) scan(ctx context.Context, worker int, o op) Result {
+	from := o.key
+	to := min(from+w.cfg.ScanLength, (w.cfg.Instance+1)*w.cfg.KeySpace)
+	acked := make([]int, to-from)
+	for i := range acked {
+		acked[i] = w.keys.acked(from + i)
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+	defer cancel()
+	start := time.Now()
+	kvs, err := w.driver.(Scanner).Scan(ctx, from, to)
+	end := time.Now()
+
+	outcome := history.OK
+	if err != nil {
+		outcome = history.Fail
+	}
+	w.measure(OpScan, from, nil, start, end, outcome, err)
+	values := map[int]*int{}
+	for _, kv := range kvs {
+		values[kv.Key] = history.IntPtr(kv.Value)
+	}
+	for key := from; key < to; key++ {
+		w.record(worker, history.Read, key, values[key], start, end, outcome, err)
+		if err == nil {
+			w.verifyRead(key, values[key], acked[key-from])
+		}
+	}
+	return Result{err: err, ts: o.ts}
+}
+
+// report emits the intended and achieved rate, the saturation of the pool
+// and the latency percentiles once per second until ctx is cancelled. Queued
+// and dropped writes mean the backend cannot keep up with the schedule.
+func (w *Workload) report(ctx context.Context, pc *pacer, pl *pool) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	last := pc.start
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			stats := pl.snapshot()
+			achieved := float64(stats.accepted) / now.Sub(last).Seconds()
+			w.reporter.Rate(RateEvent{
+				Time:     now,
+				Intended: pc.intended(last, now),
+				Achieved: achieved,
+				InFlight: stats.inFlight,
+				Queued:   stats.queued,
+				Dropped:  stats.dropped,
+			})
+			for _, e := range latencyEvents(now, w.latencies.snapshot()) {
+				w.reporter.Latency(e)
+			}
+			last = now
+		}
+	}
+}
+
+// consume aggregates results into wall-clock buckets. A bucket is emitted
+// once every write scheduled in it has either completed or timed out.
+func (w *Workload) consume(results chan Result, start time.Time) {
+	agg := newWindows(w.reporter, w.cfg.BucketSize, w.cfg.Windows, start)
+	ticker := time.NewTicker(w.cfg.BucketSize)
+	defer ticker.Stop()
+
+	first_success := !w.cfg.SkipUntilFirstSuccess
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				agg.flush()
+				return
+			}
+			if !first_success {
+				if result.err == nil {
+					first_success = true
+				} else {
+					continue
+				}
+			}
+			agg.add(result)
+		case now := <-ticker.C:
+			agg.emitUntil(now.Add(-w.cfg.OpTimeout))
+		}
+	}
+}
+
+// observe records the latency and outcome of one operation the given worker
+// started at start, and appends it to the history.
+func (w *Workload) observe(worker int, opType OpType, key int, value *int, start time.Time, outcome history.Outcome, err error) {
+	end := time.Now()
+	w.measure(opType, key, value, start, end, outcome, err)
+	w.record(worker, historyFunc(opType), key, value, start, end, outcome, err)
+}
+
+// historyFunc returns the function an operation of opType is recorded as.
+func historyFunc(opType OpType) history.Func {
+	switch opType {
+	case OpDelete:
+		return history.Delete
+	case OpRead, OpCheck:
+		return history.Read
+	}
+	return history.Write
+}
+
+// measure records the latency and outcome of one operation in the stats and
+// reports it.
+func (w *Workload) measure(opType OpType, key int, value *int, start, end time.Time, outcome history.Outcome, err error) {
+	latency := end.Sub(start)
+	label := string(outcome)
+	if rejected(opType, err) {
+		label = "rejected"
+	}
+	w.latencies.record(opType, latency)
+	w.tally.operation(opType, outcome, rejected(opType, err))
+	opsTotal.WithLabelValues(w.cfg.Backend, string(opType), label).Inc()
+	opDuration.WithLabelValues(w.cfg.Backend, string(opType)).Observe(latency.Seconds())
+
+	e := OperationEvent{
+		Time:      end,
+		Op:        opType,
+		Key:       key,
+		Value:     value,
+		Outcome:   string(outcome),
+		LatencyMs: durationToMs(latency),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	w.reporter.Operation(e)
+}
+
+// record appends one operation of the given worker to the history, and
+// retires the process of the worker after an unknown outcome.
+func (w *Workload) record(worker int, f history.Func, key int, value *int, start, end time.Time, outcome history.Outcome, err error) {
+	op := history.Op{
+		F:        f,
+		Key:      key,
+		Value:    value,
+		Outcome:  outcome,
+		Invoke:   start,
+		Complete: end,
+	}
+	if err != nil {
+		op.Error = err.Error()
+	}
+	w.recordOp(worker, op)
+}
+
+// recordOp appends op to the history as an operation of the given worker,
+// and retires the process of the worker after an unknown outcome. It returns
+// op with its process and client filled in.
+func (w *Workload) recordOp(worker int, op history.Op) history.Op {
+	op.Process = w.processes[worker]
+	op.Client = w.cfg.Client
+	if w.cfg.History != nil {
+		if err := w.cfg.History.Record(op); err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording history: %s\n", err)
+		}
+	}
+	// The process of an operation with an unknown outcome may still be
+	// running it, so the worker continues as a new process.
+	if op.Outcome == history.Info {
+		w.processes[worker] += len(w.processes)
+	}
+	return op
+}
+
+// resume restores the state of the run that saved w.cfg.Resume. Writes that
+// were in flight or may have been issued after the checkpoint get an unknown
+// outcome, each in its own history process from the first one the history
+// does not use yet, so the processes of the run start after them.
+func (w *Workload) resume() {
+	process := 0
+	if w.cfg.History != nil {
+		process = w.cfg.History.NextProcess()
+	}
+	now := time.Now()
+	for _, pending := range w.keys.restore(w.cfg.Resume, w.plan) {
+		key, value := pending.Key, pending.Value
+		w.keys.write(pending.Sequence, key, value, history.Info)
+		f := history.Write
+		if pending.Delete {
+			f = history.Delete
+		}
+		if w.cfg.History != nil {
+			if err := w.cfg.History.Record(history.Op{
+				Process:  process,
+				Client:   w.cfg.Client,
+				F:        f,
+				Key:      key,
+				Value:    history.IntPtr(value),
+				Outcome:  history.Info,
+				Invoke:   pending.Invoke,
+				Complete: now,
+				Error:    "outcome lost in restart",
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "Error recording history: %s\n", err)
+			}
+		}
+		process++
+	}
+}
+
+// checkpoint saves the state of the run every CheckpointInterval until ctx
+// is cancelled.
+func (w *Workload) checkpoint(ctx context.Context, cp *checkpointer) {
+	ticker := time.NewTicker(w.cfg.CheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cp.save(w.keys); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving checkpoint: %s\n", err)
+			}
+		}
+	}
+}
+
+// verify waits for the backend to become healthy once writing stopped and
+// reads every key the workload wrote to, classifying it as intact, lost,
+// stale or unexpected. It returns nil if the driver cannot read.
+func (w *Workload) verify(ctx context.Context) *DurabilityReport {
+	healthy, waited := w.waitHealthy(ctx)
+	report := &DurabilityReport{Healthy: healthy, WaitMs: durationToMs(waited)}
+	for _, key := range w.keys.touched() {
+		value, err := w.finalRead(ctx, key)
+		if errors.Is(err, ErrReadUnsupported) {
+			return nil
+		}
+		if err != nil {
+			report.Unreadable++
+			continue
+		}
+
+		kind := ""
+		switch w.keys.classify(key, value) {
+		case Intact:
+			report.Intact++
+		case Lost:
+			report.Lost++
+			kind = AnomalyLostWrite
+		case Stale:
+			report.Stale++
+			kind = AnomalyLostWrite
+		case Unexpected:
+			report.Unexpected++
+			kind = AnomalyUnexpected
+		}
+		if kind != "" {
+			inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+			w.tally.anomaly(kind)
+			w.reporter.Anomaly(AnomalyEvent{
+				Time:     time.Now(),
+				Kind:     kind,
+				Key:      key,
+				Expected: w.keys.acked(key),
+				Actual:   value,
+			})
+		}
+	}
+	return report
+}
+
+// waitHealthy polls the probe until the backend is healthy or the recovery
+// timeout passed, and returns how long it waited.
+func (w *Workload) waitHealthy(ctx context.Context) (bool, time.Duration) {
+	probe := w.cfg.Probe
+	if probe == nil {
+		probe = DriverProbe{}
+	}
+	start := time.Now()
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+		err := probe.Healthy(probeCtx, w.driver)
+		cancel()
+		if err == nil {
+			return true, time.Since(start)
+		}
+		if time.Since(start) >= w.cfg.RecoveryTimeout {
+			return false, time.Since(start)
+		}
+		select {
+		case <-ctx.Done():
+			return false, time.Since(start)
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+// finalRead reads key, retrying failed reads a few times. The value is nil
+// if the key does not exist.
+func (w *Workload) finalRead(ctx context.Context, key int) (*int, error) {
+	worker := len(w.processes) - 1
+	var err error
+	for attempt := 0; attempt < finalReadAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(probeInterval)
+		}
+		readCtx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+		start := time.Now()
+		var actual int
+		actual, err = w.driver.Read(readCtx, key)
+		cancel()
+		switch {
+		case errors.Is(err, ErrReadUnsupported):
+			return nil, err
+		case err == nil:
+			w.observe(worker, OpCheck, key, &actual, start, history.OK, nil)
+			return &actual, nil
+		case errors.Is(err, ErrNotFound):
+			w.observe(worker, OpCheck, key, nil, start, history.OK, err)
+			return nil, nil
+		}
+		w.observe(worker, OpCheck, key, nil, start, history.Fail, err)
+	}
+	return nil, err
+}
+
+// checkLinearizability runs the linearizability checker over the recorded
+// history and reports every key that has no linearization.
+func (w *Workload) checkLinearizability() (checker.Validity, error) {
+	ops, err := w.cfg.History.Ops()
+	if err != nil {
+		return "", fmt.Errorf("loading history: %w", err)
+	}
+	result := checker.Linearizable(ops, w.cfg.LinearizabilityTimeout)
+	for _, a := range result.Anomalies {
+		w.tally.anomaly(a.Kind)
+		w.reporter.Anomaly(AnomalyEvent{
+			Time:    time.Now(),
+			Kind:    a.Kind,
+			Key:     a.Key,
+			Message: a.Message,
+			Ops:     a.Ops,
+		})
+	}
+	return result.Valid, nil
+}
+
+func (w *Workload) check(ctx context.Context) {
+	// Keep checking the consistency between the map and the database
+	for ctx.Err() == nil {
+		keys, err := w.checkPass(ctx)
+		if errors.Is(err, ErrReadUnsupported) {
+			return
+		}
+		if w.cfg.CrossCheck && err == nil {
+			w.crossCheckPass(ctx)
+		}
+		if err == nil && keys > 0 {
+			checkerKeys.WithLabelValues(w.cfg.Backend).Set(float64(keys))
+			checkerPassesTotal.WithLabelValues(w.cfg.Backend).Inc()
+		}
+	}
+}
+
+// checkPass reads every key the workload wrote to once and reports the keys
+// that lost an acknowledged write or hold the value of a failed write. It
+// returns the number of keys checked, and ctx.Err() or ErrReadUnsupported if
+// the pass was cut short.
+func (w *Workload) checkPass(ctx context.Context) (int, error) {
+	worker := len(w.processes) - 1
+	checked := 0
+	for _, key := range w.keys.touched() {
+		if ctx.Err() != nil {
+			// The run is over, the read was cut short.
+			return checked, ctx.Err()
+		}
+		// A read can only miss writes acknowledged before it started.
+		acked := w.keys.acked(key)
+		readCtx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+		start := time.Now()
+		actual, err := w.driver.Read(readCtx, key)
+		cancel()
+		if errors.Is(err, ErrReadUnsupported) {
+			return checked, err
+		}
+		if ctx.Err() != nil {
+			return checked, ctx.Err()
+		}
+		checked++
+
+		var value *int
+		switch {
+		case err == nil:
+			value = &actual
+			w.observe(worker, OpCheck, key, value, start, history.OK, nil)
+		case errors.Is(err, ErrNotFound):
+			w.observe(worker, OpCheck, key, nil, start, history.OK, err)
+		default:
+			w.observe(worker, OpCheck, key, nil, start, history.Fail, err)
+			continue
+		}
+		w.verifyRead(key, value, acked)
+	}
+	return checked, nil
+}
+
+// verifyRead reports the value read from key by a read started when acked
+// was its highest acknowledged value, if it misses that write or was written
+// by a write that failed.
+func (w *Workload) verifyRead(key int, value *int, acked int) {
+	kind := w.keys.read(key, value, acked)
+	if kind == "" {
+		return
+	}
+	inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+	w.tally.anomaly(kind)
+	w.reporter.Anomaly(AnomalyEvent{
+		Time:     time.Now(),
+		Kind:     kind,
+		Key:      key,
+		Expected: acked,
+		Actual:   value,
+	})
+}
+
+// crossCheckPass reads every key of the other instances once. Their writes
+// are unknown here, but values only grow per key, so a key that returns a
+// smaller value than read before, or none, lost a write.
+func (w *Workload) crossCheckPass(ctx context.Context) {
+	worker := len(w.processes) - 1
+	for instance := 0; instance < w.cfg.Instances; instance++ {
+		if instance == w.cfg.Instance {
+			continue
+		}
+		for key := instance * w.cfg.KeySpace; key < (instance+1)*w.cfg.KeySpace; key++ {
+			if ctx.Err() != nil {
+				return
+			}
+			readCtx, cancel := context.WithTimeout(ctx, w.cfg.OpTimeout)
+			start := time.Now()
+			actual, err := w.driver.Read(readCtx, key)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+
+			var value *int
+			switch {
+			case err == nil:
+				value = &actual
+				w.observe(worker, OpCheck, key, value, start, history.OK, nil)
+			case errors.Is(err, ErrNotFound):
+				w.observe(worker, OpCheck, key, nil, start, history.OK, err)
+			default:
+				w.observe(worker, OpCheck, key, nil, start, history.Fail, err)
+				continue
+			}
+
+			seen, ok := w.foreign[key]
+			if value != nil && (!ok || *value > seen) {
+				w.foreign[key] = *value
+			}
+			// The instances share the mix, so the others may delete keys
+			// if this one does.
+			if !ok || (value != nil && *value >= seen) || (value == nil && w.deletes()) {
+				continue
+			}
+			inconsistenciesTotal.WithLabelValues(w.cfg.Backend).Inc()
+			w.tally.anomaly(AnomalyLostWrite)
+			w.reporter.Anomaly(AnomalyEvent{
+				Time:     time.Now(),
+				Kind:     AnomalyLostWrite,
+				Key:      key,
+				Expected: seen,
+				Actual:   value,
+			})
+		}
+	}
+}